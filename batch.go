@@ -0,0 +1,175 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/types"
+	"github.com/horm-database/common/util"
+	"github.com/horm-database/go-horm/horm/codec"
+	"github.com/horm-database/orm/obj"
+)
+
+// Batch 多执行单元，同层级的执行单元并发执行，总耗时取决于最慢的执行单元
+type Batch struct {
+	units []*ORM
+}
+
+// BatchResult 执行单元的执行结果
+type BatchResult struct {
+	IsNil  bool          // 结果为空
+	Error  error         // 执行错误
+	Result interface{}   // 执行结果
+	Detail *proto.Detail // 查询细节信息
+
+	coder      codec.Codec
+	resultType int8
+	requestID  uint64
+}
+
+// NewBatch 创建多执行单元
+func NewBatch(units ...*ORM) *Batch {
+	return &Batch{units: units}
+}
+
+// Add 添加执行单元，执行单元结果以执行单元的别名为 key 返回，未设置别名则以执行单元名为 key，
+// 例如 Name("user(u)") 的 key 为 u，Name("user") 的 key 为 user。
+func (b *Batch) Add(units ...*ORM) *Batch {
+	b.units = append(b.units, units...)
+	return b
+}
+
+// Exec 并发执行所有执行单元，返回以执行单元别名为 key 的结果，执行单元自身的错误记录在各自结果的 Error 中。
+// 注意：事务中的执行单元共用同一个事务连接，会依次执行。
+func (b *Batch) Exec(ctx context.Context) (map[string]*BatchResult, error) {
+	if len(b.units) == 0 {
+		return nil, errs.New(errs.ErrReqParamInvalid, "batch has no query unit")
+	}
+
+	defer func() {
+		for _, unit := range b.units {
+			unit.reset()
+		}
+	}()
+
+	ret := make(map[string]*BatchResult, len(b.units))
+	nodes := make([]*obj.Tree, len(b.units))
+	keys := make([]string, len(b.units))
+
+	var last *obj.Tree
+
+	for k, unit := range b.units {
+		key := unitKey(unit)
+		if _, ok := ret[key]; ok {
+			return nil, errs.Newf(errs.ErrReqParamInvalid, "batch query unit key [%s] is duplicated", key)
+		}
+
+		result := BatchResult{
+			coder:      unit.query.GetCoder(),
+			resultType: unit.query.ResultType,
+			requestID:  unit.query.RequestID,
+		}
+
+		ret[key] = &result
+		keys[k] = key
+
+		result.Error = unit.checkExec()
+		if result.Error != nil {
+			continue
+		}
+
+		node, err := unit.newTree()
+		if err != nil {
+			result.Error = err
+			continue
+		}
+
+		if last != nil {
+			last.Next = node
+			node.Last = last
+		}
+
+		last = node
+		nodes[k] = node
+	}
+
+	var wg sync.WaitGroup
+
+	for _, node := range nodes {
+		if node == nil || node.InTrans {
+			continue
+		}
+
+		wg.Add(1)
+		go func(node *obj.Tree) {
+			defer wg.Done()
+			execNode(ctx, node)
+		}(node)
+	}
+
+	for _, node := range nodes {
+		if node != nil && node.InTrans {
+			execNode(ctx, node)
+		}
+	}
+
+	wg.Wait()
+
+	for k, node := range nodes {
+		if node == nil {
+			continue
+		}
+
+		result := ret[keys[k]]
+		result.IsNil, result.Result, result.Error = ParseResult(node)
+		result.Detail = node.Detail
+	}
+
+	return ret, nil
+}
+
+// Decode 将执行结果解码到 retReceiver
+func (r *BatchResult) Decode(retReceiver ...interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+
+	err := r.coder.Decode(r.resultType, r.Result, retReceiver)
+	if err != nil {
+		return errs.Newf(errs.ErrClientDecode,
+			"[request_id=%d] %v, result=[%s]", r.requestID, err, types.ToString(r.Result))
+	}
+
+	return nil
+}
+
+// unitKey 执行单元结果 key，优先取别名
+func unitKey(o *ORM) string {
+	name, alias := util.Alias(o.query.Unit.Name)
+	if alias != "" {
+		return alias
+	}
+
+	if name != "" {
+		return name
+	}
+
+	return fmt.Sprint(o.query.Unit.Shard)
+}
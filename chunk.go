@@ -0,0 +1,132 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/util"
+	"github.com/horm-database/orm/database"
+)
+
+// ErrBreak Chunk 等批处理函数返回 ErrBreak 时提前结束，不作为错误返回
+var ErrBreak = database.ErrBreak
+
+// defaultChunkKey Chunk 未指定排序时默认的分页列
+const defaultChunkKey = "id"
+
+// Chunk 按批次遍历满足条件的所有数据，每批最多 size 条，依次交由 fn 处理，fn 返回 ErrBreak 时提前结束。
+// 分页采用 keyset 方式（WHERE id > last ORDER BY id LIMIT size），不使用 OFFSET，翻页性能不随页数下降，
+// elastic 通过 search_after 实现。分页列取 Order 的第一列（必须唯一，其余排序列会被忽略），未设置时为 id 升序，例如：
+//
+//	err := db.Session().Name("user").FindAll(horm.Where{"status": 1}).Order("id").
+//		Chunk(ctx, 500, func(rows []map[string]interface{}) error {
+//			return handle(rows)
+//		})
+func (o *ORM) Chunk(ctx context.Context, size int, fn func(rows []map[string]interface{}) error) error {
+	defer o.reset()
+
+	if err := o.checkExec(); err != nil {
+		return err
+	}
+
+	if size <= 0 {
+		return errs.Newf(errs.ErrReqParamInvalid, "chunk size must be greater than 0, size=%d", size)
+	}
+
+	base := o.query.Unit
+
+	key, ascending := defaultChunkKey, true
+	if orders := util.FormatOrders(base.Order); len(orders) > 0 {
+		key, ascending = orders[0].Field, orders[0].Ascending
+	}
+
+	order, operator := key, ">"
+	if !ascending {
+		order, operator = "-"+key, "<"
+	}
+
+	isElastic := o.db.Addr != nil && o.db.Addr.Type == consts.DBTypeElastic
+
+	var last interface{}
+
+	for {
+		unit := *base
+		unit.Op = consts.OpFindAll
+		unit.Order = []string{order}
+		unit.Page = 0
+		unit.Size = size
+		unit.From = 0
+		unit.Where = copyMap(base.Where)
+		unit.Params = copyMap(base.Params)
+
+		if last != nil {
+			if isElastic {
+				unit.Params["search_after"] = []interface{}{last}
+			} else {
+				unit.Where[key+operator] = last
+			}
+		}
+
+		rows, err := o.queryUnit(ctx, &unit)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) == 0 {
+			return nil
+		}
+
+		last = rows[len(rows)-1][key]
+		if last == nil {
+			return errs.Newf(errs.ErrReqParamInvalid, "chunk key [%s] not found in result", key)
+		}
+
+		err = fn(rows)
+		if err == ErrBreak {
+			return nil
+		}
+
+		if err != nil || len(rows) < size {
+			return err
+		}
+	}
+}
+
+// queryUnit 执行查询单元，返回原始结果记录
+func (o *ORM) queryUnit(ctx context.Context, unit *proto.Unit) ([]map[string]interface{}, error) {
+	tree, err := o.newTreeFromUnit(unit)
+	if err != nil {
+		return nil, err
+	}
+
+	execNode(ctx, tree)
+	if tree.Error != nil {
+		return nil, tree.Error
+	}
+
+	return toRows(tree.Result), nil
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		ret[k] = v
+	}
+	return ret
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/orm/obj"
+)
+
+// errDryRun 试运行时 recorder 记录请求后返回的错误，用于中断查询
+var errDryRun = errors.New("elastic dry run")
+
+// DryRun 试运行，查询与正式执行走同一套请求构建逻辑，由 recorder 记录将要发往 es 的请求（方法 + 路径）及请求体，不访问 es
+func (q *Query) DryRun(ctx context.Context) ([]*obj.Statement, error) {
+	q.recorder = &recorder{}
+
+	_, _, _, err := q.Query(ctx)
+	if len(q.recorder.statements) > 0 { // 第一个请求被 recorder 中断，返回的错误忽略
+		return q.recorder.statements, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, errs.Newf(errs.ErrDBParams, "elastic dry run not support op %s", q.OP)
+}
+
+// recorder 试运行时替代 es 连接，记录请求后返回 errDryRun，不访问 es
+type recorder struct {
+	statements []*obj.Statement
+}
+
+// Do 实现 elastic v7 Doer 接口
+func (r *recorder) Do(request *http.Request) (*http.Response, error) {
+	statement := obj.Statement{Query: request.Method + " " + request.URL.RequestURI()}
+
+	if request.Body != nil {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		// 请求体为 json，bulk 请求体为每行一个 json
+		for _, line := range bytes.Split(body, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+
+			var v interface{}
+
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+
+			if err = decoder.Decode(&v); err != nil {
+				v = string(line)
+			}
+
+			statement.Params = append(statement.Params, v)
+		}
+	}
+
+	r.statements = append(r.statements, &statement)
+
+	return nil, errDryRun
+}
+
+// RoundTrip 实现 http.RoundTripper 接口，用于 elastic v6
+func (r *recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	return r.Do(request)
+}
@@ -16,6 +16,7 @@ package elastic
 
 import (
 	"context"
+	"net/http"
 	"strings"
 
 	"github.com/horm-database/common/consts"
@@ -28,10 +29,27 @@ import (
 	esv7 "github.com/olivere/elastic/v7"
 )
 
+// newClientV6 创建 6.x 版本的 elastic.Client，试运行时请求由 recorder 记录，不发往 es
+func (q *Query) newClientV6(isRead bool) (*esv6.Client, error) {
+	if q.recorder != nil {
+		return esv6.NewSimpleClient(esv6.SetTraceLog(q), esv6.SetHttpClient(&http.Client{Transport: q.recorder}))
+	}
+
+	return client.NewClientV6(isRead, q.Addr, esv6.SetTraceLog(q))
+}
+
+// newClientV7 创建 7.x 版本的 elastic.Client，试运行时请求由 recorder 记录，不发往 es
+func (q *Query) newClientV7(isRead bool) (*esv7.Client, error) {
+	if q.recorder != nil {
+		return esv7.NewSimpleClient(esv7.SetTraceLog(q), esv7.SetHttpClient(q.recorder))
+	}
+
+	return client.NewClientV7(isRead, q.Addr, esv7.SetTraceLog(q))
+}
+
 // 插入新数据（V7 SDK 版本兼容 V6 操作）
 func (q *Query) insert(ctx context.Context, op, id string) (*proto.ModRet, *proto.Detail, bool, error) {
-	opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-	clientV7, err := client.NewClientV7(false, q.Addr, opts...)
+	clientV7, err := q.newClientV7(false)
 	if err != nil {
 		return nil, nil, false, q.formatError("insert.NewClient", "", nil, err)
 	}
@@ -69,8 +87,7 @@ func (q *Query) insert(ctx context.Context, op, id string) (*proto.ModRet, *prot
 // 批量插入新数据，（V7 SDK 版本兼容 V6 操作）
 func (q *Query) bulkInsert(ctx context.Context, op string,
 	esIds []string) ([]*proto.ModRet, *proto.Detail, bool, error) {
-	opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-	clientV7, err := client.NewClientV7(false, q.Addr, opts...)
+	clientV7, err := q.newClientV7(false)
 	if err != nil {
 		return nil, nil, false, q.formatError("bulkInsert.NewClient", "", nil, err)
 	}
@@ -135,8 +152,7 @@ func (q *Query) updateByID(ctx context.Context, id string) (*proto.ModRet, *prot
 	var clientV7 *esv7.Client
 	var retV7 *esv7.UpdateResponse
 
-	opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-	clientV7, err = client.NewClientV7(false, q.Addr, opts...)
+	clientV7, err = q.newClientV7(false)
 
 	if err != nil {
 		return nil, nil, false, q.formatError("updateByID.NewClient", "", nil, err)
@@ -178,11 +194,9 @@ func (q *Query) updateByQuery(ctx context.Context) (*proto.ModRet, *proto.Detail
 	var retV7 *esv7.BulkIndexByScrollResponse
 
 	if q.Addr.Version == ElasticV6 {
-		opts := []esv6.ClientOptionFunc{esv6.SetTraceLog(q)}
-		clientV6, err = client.NewClientV6(false, q.Addr, opts...)
+		clientV6, err = q.newClientV6(false)
 	} else {
-		opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-		clientV7, err = client.NewClientV7(false, q.Addr, opts...)
+		clientV7, err = q.newClientV7(false)
 	}
 
 	if err != nil {
@@ -232,8 +246,7 @@ func (q *Query) updateByQuery(ctx context.Context) (*proto.ModRet, *proto.Detail
 
 // 通过ID删除数据（V7 SDK 版本兼容 V6 操作）
 func (q *Query) deleteByID(ctx context.Context, id string) (*proto.ModRet, *proto.Detail, bool, error) {
-	opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-	clientV7, err := client.NewClientV7(false, q.Addr, opts...)
+	clientV7, err := q.newClientV7(false)
 
 	if err != nil {
 		return nil, nil, false, q.formatError("deleteByID.NewClient", "", nil, err)
@@ -252,8 +265,7 @@ func (q *Query) deleteByID(ctx context.Context, id string) (*proto.ModRet, *prot
 
 // 通过 query 条件删除数据（V7 SDK 版本兼容 V6 操作）
 func (q *Query) deleteByQuery(ctx context.Context) (*proto.ModRet, *proto.Detail, bool, error) {
-	opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-	clientV7, err := client.NewClientV7(false, q.Addr, opts...)
+	clientV7, err := q.newClientV7(false)
 
 	if err != nil {
 		return nil, nil, false, q.formatError("deleteByQuery.NewClient", "", nil, err)
@@ -283,11 +295,9 @@ func (q *Query) search(ctx context.Context) ([]map[string]interface{}, *proto.De
 	var retV7 *esv7.SearchResult
 
 	if q.Addr.Version == ElasticV6 {
-		opts := []esv6.ClientOptionFunc{esv6.SetTraceLog(q)}
-		clientV6, err = client.NewClientV6(true, q.Addr, opts...)
+		clientV6, err = q.newClientV6(true)
 	} else {
-		opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-		clientV7, err = client.NewClientV7(true, q.Addr, opts...)
+		clientV7, err = q.newClientV7(true)
 	}
 
 	if err != nil {
@@ -328,11 +338,9 @@ func (q *Query) scrollByQuery(ctx context.Context) ([]map[string]interface{}, *p
 	var retV7 *esv7.SearchResult
 
 	if q.Addr.Version == ElasticV6 {
-		opts := []esv6.ClientOptionFunc{esv6.SetTraceLog(q)}
-		clientV6, err = client.NewClientV6(true, q.Addr, opts...)
+		clientV6, err = q.newClientV6(true)
 	} else {
-		opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-		clientV7, err = client.NewClientV7(true, q.Addr, opts...)
+		clientV7, err = q.newClientV7(true)
 	}
 
 	if err != nil {
@@ -394,11 +402,9 @@ func (q *Query) scrollByScrollID(ctx context.Context) ([]map[string]interface{},
 	var retV7 *esv7.SearchResult
 
 	if q.Addr.Version == ElasticV6 {
-		opts := []esv6.ClientOptionFunc{esv6.SetTraceLog(q)}
-		clientV6, err = client.NewClientV6(true, q.Addr, opts...)
+		clientV6, err = q.newClientV6(true)
 	} else {
-		opts := []esv7.ClientOptionFunc{esv7.SetTraceLog(q)}
-		clientV7, err = client.NewClientV7(true, q.Addr, opts...)
+		clientV7, err = q.newClientV7(true)
 	}
 
 	if err != nil {
@@ -406,9 +412,17 @@ func (q *Query) scrollByScrollID(ctx context.Context) ([]map[string]interface{},
 	}
 
 	if clientV6 != nil {
-		retV6, err = clientV6.Scroll(q.Index...).Type(q.Type).ScrollId(q.Scroll.ID).Do(ctx)
+		scrollSearch := clientV6.Scroll(q.Index...).Type(q.Type).ScrollId(q.Scroll.ID)
+		if q.Scroll.Info != "" { // 续期滚动上下文
+			scrollSearch.Scroll(q.Scroll.Info)
+		}
+		retV6, err = scrollSearch.Do(ctx)
 	} else {
-		retV7, err = clientV7.Scroll(q.Index...).ScrollId(q.Scroll.ID).Do(ctx)
+		scrollSearch := clientV7.Scroll(q.Index...).ScrollId(q.Scroll.ID)
+		if q.Scroll.Info != "" {
+			scrollSearch.Scroll(q.Scroll.Info)
+		}
+		retV7, err = scrollSearch.Do(ctx)
 	}
 
 	if err != nil {
@@ -30,7 +30,7 @@ import (
 func (q *Query) logError(loc, id string, source interface{}, err error) error {
 	e := q.formatError(loc, id, source, err)
 
-	if !log.OmitError(q.Addr) {
+	if !log.OmitError(q.Addr) && q.recorder == nil { // 试运行中断查询的错误不记录
 		q.TimeLog.Error(errs.Code(e), "elastic query error: ", errs.Msg(e))
 	}
 
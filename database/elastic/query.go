@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/log"
 	"github.com/horm-database/common/proto"
 	"github.com/horm-database/common/proto/plugin"
@@ -57,6 +58,10 @@ type Query struct {
 	// Params
 	Params types.Map
 
+	// 游标分页
+	Cursor     string
+	cursorMode bool
+
 	// 日志
 	logStep         int8
 	reqLog, respLog string
@@ -65,6 +70,8 @@ type Query struct {
 	TimeLog *log.TimeLog
 
 	QL string
+
+	recorder *recorder // 试运行请求记录器，不为空时请求只记录，不发往 es
 }
 
 func (q *Query) SetParams(req *plugin.Request,
@@ -94,12 +101,28 @@ func (q *Query) SetParams(req *plugin.Request,
 
 	q.Routing, _ = req.Params.GetString("routing")
 
+	lock, _ := req.Params.GetString("lock")
+	lockWait, _ := req.Params.GetString("lock_wait")
+	if lock != "" || lockWait != "" {
+		return errs.Newf(errs.ErrReqParamInvalid, "elastic does not support lock [%s %s]", lock, lockWait)
+	}
+
 	q.HighLights, err = getHighLightParam(req.Params)
 	if err != nil {
 		return err
 	}
 
 	q.Params = req.Params
+	q.Cursor, q.cursorMode = req.Params.GetString("cursor")
+
+	if upsert, _ := req.Params.GetBool("upsert"); upsert && q.OP == consts.OpInsert { // upsert 即 index，文档存在则覆盖
+		if _, ok := req.Params["update_columns"]; ok {
+			return errs.Newf(errs.ErrReqParamInvalid, "elastic does not support upsert with update_columns")
+		}
+
+		q.OP = consts.OpReplace
+	}
+
 	return nil
 }
 
@@ -107,21 +130,7 @@ func (q *Query) SetParams(req *plugin.Request,
 func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, error) {
 	q.TimeLog = ol.NewTimeLog(ctx, q.Addr)
 
-	var esIds []string
-
-	if len(q.Where) > 0 {
-		id, ok := q.Where["_id"]
-		if ok && id != nil {
-			switch val := id.(type) {
-			case []interface{}:
-				for _, v := range val {
-					esIds = append(esIds, types.ToString(v))
-				}
-			default:
-				esIds = append(esIds, types.ToString(id))
-			}
-		}
-	}
+	esIds := q.getIDs()
 
 	if q.Type == "" {
 		q.Type = "_doc"
@@ -165,6 +174,8 @@ func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 			} else if q.Scroll.Info != "" {
 				return q.scrollByQuery(ctx)
 			}
+		} else if q.cursorMode {
+			return q.searchByCursor(ctx)
 		} else {
 			return q.search(ctx)
 		}
@@ -173,6 +184,27 @@ func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 	return nil, nil, false, nil
 }
 
+// getIDs 获取 where 条件中的 _id
+func (q *Query) getIDs() []string {
+	var esIds []string
+
+	if len(q.Where) > 0 {
+		id, ok := q.Where["_id"]
+		if ok && id != nil {
+			switch val := id.(type) {
+			case []interface{}:
+				for _, v := range val {
+					esIds = append(esIds, types.ToString(v))
+				}
+			default:
+				esIds = append(esIds, types.ToString(id))
+			}
+		}
+	}
+
+	return esIds
+}
+
 // GetQuery 获取 es 的查询语句
 func (q *Query) GetQuery() string {
 	return q.QL
@@ -15,12 +15,16 @@
 package elastic
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/json"
 	"github.com/horm-database/common/proto"
 	"github.com/horm-database/common/proto/elastic"
+	"github.com/horm-database/common/types"
 	"github.com/horm-database/common/util"
+	"github.com/horm-database/orm/obj"
 	"github.com/samber/lo"
 
 	esv6 "github.com/olivere/elastic/v6"
@@ -65,6 +69,16 @@ func (q *Query) getSearchSource() (interface{}, error) {
 		searchSource.Size(q.Size).From(int(q.From))
 	}
 
+	if searchAfter, ok := q.Params["search_after"]; ok && searchAfter != nil { // 基于排序值翻页
+		values, err := types.ToArray(searchAfter)
+		if err != nil {
+			return nil, fmt.Errorf("search_after must be an array: %s", err.Error())
+		}
+		if len(values) > 0 {
+			searchSource.SearchAfter(values...)
+		}
+	}
+
 	iSearchSource, err := searchSource.Source()
 	if err != nil {
 		return nil, fmt.Errorf("search source error: %s", err.Error())
@@ -252,3 +266,51 @@ func unknownFieldError(desc string, params map[string]interface{}, supportField
 
 	return nil
 }
+
+// searchByCursor 游标分页查询，通过 search_after 定位，多查询一条用于判断是否还有下一页
+func (q *Query) searchByCursor(ctx context.Context) ([]map[string]interface{}, *proto.Detail, bool, error) {
+	size := q.Size
+	if size <= 0 {
+		return nil, nil, false, errs.Newf(errs.ErrReqParamInvalid,
+			"cursor pagination size must be greater than 0, size=%d", size)
+	}
+
+	orders := obj.CursorOrders(q.Order)
+	if len(q.Order) == 0 {
+		q.Order = []string{obj.DefaultCursorOrder}
+	}
+
+	values, err := obj.CursorValues(q.Cursor, orders)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	params := types.Map{}
+	for k, v := range q.Params {
+		params[k] = v
+	}
+
+	if values != nil {
+		params["search_after"] = values
+	}
+
+	q.Params = params
+
+	q.Page = 0
+	q.From = 0
+	q.Size = size + 1
+
+	rows, _, _, err := q.search(ctx)
+	q.Size = size
+
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	rows, detail, err := obj.CursorResult(rows, size, orders)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return rows, detail, len(rows) == 0, nil
+}
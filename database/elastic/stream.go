@@ -0,0 +1,121 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elastic
+
+import (
+	"context"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	ol "github.com/horm-database/orm/log"
+
+	esv6 "github.com/olivere/elastic/v6"
+	esv7 "github.com/olivere/elastic/v7"
+)
+
+const (
+	defaultStreamSize   = 1000 // 流式查询每次滚动获取的文档数
+	defaultStreamScroll = "1m" // 流式查询滚动上下文保留时间
+)
+
+// Stream 通过 scroll 流式查询所有符合条件的文档，自动滚动翻页，每读取一条文档调用一次 next，next 返回错误时结束查询。
+func (q *Query) Stream(ctx context.Context, next func(row map[string]interface{}) error) error {
+	if q.OP != consts.OpFindAll {
+		return errs.Newf(errs.ErrDBParams, "elastic stream not support op %s", q.OP)
+	}
+
+	q.TimeLog = ol.NewTimeLog(ctx, q.Addr)
+
+	if q.Type == "" {
+		q.Type = "_doc"
+	}
+
+	if q.Size <= 0 {
+		q.Size = defaultStreamSize
+	}
+
+	q.Page = 0
+
+	scroll := proto.Scroll{Info: defaultStreamScroll}
+	if q.Scroll != nil {
+		scroll = *q.Scroll
+		if scroll.Info == "" {
+			scroll.Info = defaultStreamScroll
+		}
+	}
+
+	q.Scroll = &scroll
+
+	var rows []map[string]interface{}
+	var detail *proto.Detail
+	var err error
+
+	if scroll.ID != "" {
+		rows, detail, _, err = q.scrollByScrollID(ctx)
+	} else {
+		rows, detail, _, err = q.scrollByQuery(ctx)
+	}
+
+	for err == nil {
+		if detail != nil && detail.Scroll != nil && detail.Scroll.ID != "" {
+			scroll.ID = detail.Scroll.ID
+		}
+
+		for _, row := range rows {
+			if err = next(row); err != nil {
+				break
+			}
+		}
+
+		if err != nil || len(rows) < q.Size || scroll.ID == "" {
+			break
+		}
+
+		rows, detail, _, err = q.scrollByScrollID(ctx)
+	}
+
+	if scroll.ID != "" {
+		q.clearScroll(scroll.ID)
+	}
+
+	return err
+}
+
+// clearScroll 清除滚动上下文，失败仅记录日志
+func (q *Query) clearScroll(scrollID string) {
+	var err error
+
+	// 使用新的上下文，流式查询被取消时也可以清除
+	ctx := context.Background()
+
+	if q.Addr.Version == ElasticV6 {
+		var clientV6 *esv6.Client
+		clientV6, err = q.newClientV6(true)
+		if err == nil {
+			_, err = clientV6.ClearScroll(scrollID).Do(ctx)
+		}
+	} else {
+		var clientV7 *esv7.Client
+		clientV7, err = q.newClientV7(true)
+		if err == nil {
+			_, err = clientV7.ClearScroll(scrollID).Do(ctx)
+		}
+	}
+
+	if err != nil {
+		_ = q.logError("clearScroll", scrollID, nil, err)
+	}
+}
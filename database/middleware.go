@@ -0,0 +1,68 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/proto/plugin"
+	"github.com/horm-database/orm/obj"
+)
+
+// Handler 执行查询，返回查询结果、查询细节、结果是否为空、错误
+type Handler func(ctx context.Context,
+	req *plugin.Request, prop *obj.Property) (interface{}, *proto.Detail, bool, error)
+
+// Middleware 查询中间件，包裹在所有数据库查询（SetParams + Query）外层，调用 next 继续执行后续中间件和查询，
+// 可以在 next 前改写请求、鉴权、注入故障，或直接返回结果（如缓存命中）而不调用 next，也可以在 next 后处理查询结果、上报监控。
+// 流式查询（Iter）同样经过中间件，此时 next 返回的结果为 nil，数据已逐行交给调用方。
+type Middleware func(ctx context.Context, req *plugin.Request, prop *obj.Property,
+	next Handler) (interface{}, *proto.Detail, bool, error)
+
+var (
+	middlewaresLock sync.RWMutex
+	middlewares     []Middleware
+)
+
+// Use 注册查询中间件，先注册的中间件在外层，先执行。
+func Use(mw ...Middleware) {
+	middlewaresLock.Lock()
+	defer middlewaresLock.Unlock()
+
+	for _, m := range mw {
+		if m != nil {
+			middlewares = append(middlewares, m)
+		}
+	}
+}
+
+// chain 将中间件包裹在查询 handler 外层
+func chain(handler Handler) Handler {
+	middlewaresLock.RLock()
+	mws := middlewares
+	middlewaresLock.RUnlock()
+
+	for i := len(mws) - 1; i >= 0; i-- {
+		mw, next := mws[i], handler
+		handler = func(ctx context.Context,
+			req *plugin.Request, prop *obj.Property) (interface{}, *proto.Detail, bool, error) {
+			return mw(ctx, req, prop, next)
+		}
+	}
+
+	return handler
+}
@@ -62,6 +62,17 @@ func (r *Redis) SetParams(req *plugin.Request, _ *obj.Property, addr *util.DBAdd
 	r.Args = req.Args
 
 	r.Addr = addr
+
+	lock, _ := req.Params.GetString("lock")
+	lockWait, _ := req.Params.GetString("lock_wait")
+	if lock != "" || lockWait != "" {
+		return errs.Newf(errs.ErrReqParamInvalid, "redis does not support lock [%s %s]", lock, lockWait)
+	}
+
+	if upsert, _ := req.Params.GetBool("upsert"); upsert {
+		return errs.New(errs.ErrReqParamInvalid, "redis does not support upsert")
+	}
+
 	return nil
 }
 
@@ -82,9 +93,19 @@ func (r *Redis) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 	return r.parseResult(reply)
 }
 
+// DryRun 试运行，返回将要执行的 redis 命令及参数，不访问 redis
+func (r *Redis) DryRun(_ context.Context) ([]*obj.Statement, error) {
+	err := r.parseRequest()
+	if err != nil {
+		return nil, errs.NewDBf(errs.ErrRedisReqParse, "parse redis req to command error: %v", err)
+	}
+
+	return []*obj.Statement{{Query: r.Cmd, Params: util.FormatArgs(r.Args)}}, nil
+}
+
 // GetQuery 获取 redis cmmd 语句
 func (r *Redis) GetQuery() string {
-	return ""
+	return fmt.Sprintf("%s %v", r.Cmd, util.FormatArgs(r.Args))
 }
 
 func (r *Redis) do(ctx context.Context) (interface{}, bool, error) {
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/horm-database/common/consts"
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/json"
 	"github.com/horm-database/common/log"
@@ -38,6 +39,17 @@ func (q *Query) InsertToCK(ctx context.Context, desc string, batch bool, retryTi
 
 	insertSQL, keys := q.getInsertSQL(table, datas[0])
 
+	if q.dryRun { // 试运行，每条数据记录一次预编译语句的执行
+		for _, data := range datas {
+			params := make([]interface{}, len(keys))
+			for k, key := range keys {
+				params[k] = data[key]
+			}
+			q.record(insertSQL, params)
+		}
+		return nil, nil, nil
+	}
+
 	//记录错误条目
 	errIndex := map[int]error{}
 
@@ -168,10 +180,10 @@ func (q *Query) getInsertSQL(table string, attributes map[string]interface{}) (s
 	for key := range attributes {
 		keys = append(keys, key)
 		if i == 0 {
-			sqlBuilder.WriteString(columnQuote(key))
+			sqlBuilder.WriteString(columnQuote(consts.DBTypeClickHouse, key))
 		} else {
 			sqlBuilder.WriteString(`,`)
-			sqlBuilder.WriteString(columnQuote(key))
+			sqlBuilder.WriteString(columnQuote(consts.DBTypeClickHouse, key))
 		}
 
 		i++
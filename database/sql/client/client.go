@@ -24,17 +24,24 @@ import (
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/go-sql-driver/mysql"
+	"github.com/horm-database/common/consts"
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/util"
+	"github.com/lib/pq"
+	"github.com/sijms/go-ora/v2/network"
 )
 
 // Client 底层查询代理
 type Client interface {
-	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)   // 执行语句
-	Query(ctx context.Context, next NextFunc, query string, args ...interface{}) error // 查询语句
-	Prepare(ctx context.Context, query string) (*sql.Stmt, error)                      // 预绑定
-	BeginTx(ctx context.Context) error                                                 // 开启事务
-	FinishTx(err error) error                                                          // 结束事务
+	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)    // 执行语句
+	Query(ctx context.Context, next NextFunc, query string, args ...interface{}) error  // 查询语句
+	Stream(ctx context.Context, next NextFunc, query string, args ...interface{}) error // 流式查询语句
+	Prepare(ctx context.Context, query string) (*sql.Stmt, error)                       // 预绑定
+	BeginTx(ctx context.Context, opts *sql.TxOptions) error                             // 开启事务
+	FinishTx(err error) error                                                           // 结束事务
+	Savepoint(ctx context.Context, name string) error                                   // 创建事务保存点
+	RollbackTo(ctx context.Context, name string) error                                  // 回滚到事务保存点
+	ReleaseSavepoint(ctx context.Context, name string) error                            // 释放事务保存点
 }
 
 // NextFunc query 请求时，每一行数据记录执行的逻辑，
@@ -46,10 +53,72 @@ type NextFunc func(*sql.Rows) error
 var ErrBreak = errors.New("sql scan rows break")
 
 const (
-	OpExec  = 1
-	OpQuery = 2
+	OpExec   = 1
+	OpQuery  = 2
+	OpStream = 3
 )
 
+// 可重试的事务错误，事务因死锁、锁等待超时、序列化冲突失败时，可以重新执行整个事务。
+const (
+	ErrCodeLockWaitTimeout      = 1205  // mysql 锁等待超时
+	ErrCodeDeadlock             = 1213  // mysql 死锁
+	ErrCodeSerializationFailure = 40001 // postgresql 序列化冲突，SQLSTATE 40001
+
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlock             = "40P01" // postgresql 死锁
+
+	OraErrDeadlock             = 60   // oracle ORA-00060 死锁
+	OraErrSerializationFailure = 8177 // oracle ORA-08177 无法串行化访问
+)
+
+// sqlStateError 返回 SQLSTATE 的数据库错误，比如 postgresql 驱动的错误
+type sqlStateError interface {
+	SQLState() string
+}
+
+// IsRetryable 是否是可以通过重新执行整个事务解决的错误（死锁、锁等待超时、序列化冲突）
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var e *errs.Error
+	if !errors.As(err, &e) { // 错误可能被上层包装，需要解包后判断
+		return false
+	}
+
+	switch e.Code {
+	case ErrCodeLockWaitTimeout, ErrCodeDeadlock, ErrCodeSerializationFailure:
+		return true
+	}
+
+	return false
+}
+
+// pqErrorCode postgresql 错误码，可重试的事务错误转换为对应的错误码
+func pqErrorCode(e *pq.Error) int {
+	switch string(e.Code) {
+	case SQLStateSerializationFailure:
+		return ErrCodeSerializationFailure
+	case SQLStateDeadlock:
+		return ErrCodeDeadlock
+	}
+
+	return errs.ErrSQLQuery
+}
+
+// oraErrorCode oracle 错误码，可重试的事务错误转换为对应的错误码
+func oraErrorCode(e *network.OracleError) int {
+	switch e.ErrCode {
+	case OraErrDeadlock:
+		return ErrCodeDeadlock
+	case OraErrSerializationFailure:
+		return ErrCodeSerializationFailure
+	}
+
+	return e.ErrCode
+}
+
 // client 后端请求结构体
 type client struct {
 	dbType       int
@@ -70,7 +139,7 @@ func NewClient(addr *util.DBAddress) (Client, error) {
 		readTimeout:  time.Duration(addr.ReadTimeout) * time.Millisecond,
 	}
 
-	db, err := getDB(addr.Type, addr.Conn.DSN)
+	db, err := getDB(addr.Type, driverDSN(addr.Type, addr.Conn))
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +155,12 @@ func (c *client) Query(ctx context.Context, next NextFunc, query string, args ..
 	return err
 }
 
+// Stream 流式查询 query 语句，读超时只作用于语句的执行，不限制逐行读取结果的时间
+func (c *client) Stream(ctx context.Context, next NextFunc, query string, args ...interface{}) error {
+	_, err := c.invoke(ctx, OpStream, query, args, next)
+	return err
+}
+
 // Exec 执行 query 语句
 func (c *client) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	return c.invoke(ctx, OpExec, query, args, nil)
@@ -100,9 +175,13 @@ func (c *client) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
 	}
 }
 
-// BeginTx 开启事务
-func (c *client) BeginTx(ctx context.Context) (err error) {
-	c.tx, err = c.db.BeginTx(ctx, new(sql.TxOptions))
+// BeginTx 开启事务，opts 为 nil 时使用数据库默认的隔离级别
+func (c *client) BeginTx(ctx context.Context, opts *sql.TxOptions) (err error) {
+	if opts == nil {
+		opts = new(sql.TxOptions)
+	}
+
+	c.tx, err = c.db.BeginTx(ctx, opts)
 	return err
 }
 
@@ -122,13 +201,60 @@ func (c *client) FinishTx(err error) error {
 
 	err = c.tx.Commit()
 	if err != nil {
-		if e := c.tx.Rollback(); e != nil {
+		// 提交失败后事务通常已经结束，此时回滚返回 sql.ErrTxDone，可以忽略
+		if e := c.tx.Rollback(); e != nil && e != sql.ErrTxDone {
 			return errs.NewDBf(errs.ErrTransaction,
 				"rollback error: [%s], source commit error=[%s]", e.Error(), err.Error())
 		}
+		return convertError(err) // 与语句错误一致转换，提交时的序列化冲突、死锁同样可以重试
+	}
+
+	return nil
+}
+
+// Savepoint 创建事务保存点
+func (c *client) Savepoint(ctx context.Context, name string) error {
+	if err := c.checkSavepoint(); err != nil {
+		return err
+	}
+
+	_, err := c.invoke(ctx, OpExec, "SAVEPOINT "+name, nil, nil)
+	return err
+}
+
+// RollbackTo 回滚到事务保存点，保存点之后的修改全部撤销，保存点之前的修改不受影响
+func (c *client) RollbackTo(ctx context.Context, name string) error {
+	if err := c.checkSavepoint(); err != nil {
+		return err
+	}
+
+	_, err := c.invoke(ctx, OpExec, "ROLLBACK TO SAVEPOINT "+name, nil, nil)
+	return err
+}
+
+// ReleaseSavepoint 释放事务保存点
+func (c *client) ReleaseSavepoint(ctx context.Context, name string) error {
+	if err := c.checkSavepoint(); err != nil {
 		return err
 	}
 
+	if c.dbType == consts.DBTypeOracle { // oracle 不支持释放保存点，事务结束时自动释放
+		return nil
+	}
+
+	_, err := c.invoke(ctx, OpExec, "RELEASE SAVEPOINT "+name, nil, nil)
+	return err
+}
+
+func (c *client) checkSavepoint() error {
+	if c.tx == nil {
+		return errs.NewDB(errs.ErrTransaction, "savepoint must be used in transaction")
+	}
+
+	if c.dbType == consts.DBTypeClickHouse {
+		return errs.NewDB(errs.ErrTransaction, "clickhouse does not support savepoint")
+	}
+
 	return nil
 }
 
@@ -136,42 +262,33 @@ func (c *client) FinishTx(err error) error {
 func (c *client) invoke(ctx context.Context,
 	op int8, query string, args []interface{}, next NextFunc) (rsp sql.Result, err error) {
 	defer func() {
-		if err == sql.ErrNoRows {
-			err = errs.NewDB(errs.ErrSQLQuery, "sql: no rows in result set")
-			return
-		}
-
-		switch e := err.(type) {
-		case *clickhouse.Exception:
-			err = errs.NewDB(int(e.Code), e.Message)
-		case *mysql.MySQLError:
-			err = errs.NewDB(int(e.Number), e.Message)
-		case *errs.Error:
-			err = e
-		case nil:
-			err = nil
-		default:
-			err = errs.NewDB(errs.ErrClientNet, err.Error())
-		}
+		err = convertError(err)
 	}()
 
 	timeout := c.writeTimeout
 
-	if op == OpQuery {
+	if op == OpQuery || op == OpStream {
 		timeout = c.readTimeout
 	}
 
+	var timer *time.Timer
+
 	if timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, timeout)
+		if op == OpStream { // 流式查询的结果集与 ctx 绑定，不能在语句执行后取消，由定时器只限制语句的执行时间
+			ctx, cancel = context.WithCancel(ctx)
+			timer = time.AfterFunc(timeout, cancel)
+		} else {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
 		defer cancel()
 	}
 
 	begin := time.Now()
 
 	switch op {
-	case OpQuery:
-		err = c.query(ctx, query, args, next)
+	case OpQuery, OpStream:
+		err = c.query(ctx, query, args, next, timer)
 	case OpExec:
 		if c.tx != nil {
 			rsp, err = c.tx.ExecContext(ctx, query, args...)
@@ -191,7 +308,38 @@ func (c *client) invoke(ctx context.Context,
 	return
 }
 
-func (c *client) query(ctx context.Context, query string, args []interface{}, next NextFunc) (err error) {
+// convertError 将驱动返回的错误转换为 errs.Error，可重试的事务错误转换为对应的错误码
+func convertError(err error) error {
+	if err == sql.ErrNoRows {
+		return errs.NewDB(errs.ErrSQLQuery, "sql: no rows in result set")
+	}
+
+	switch e := err.(type) {
+	case *clickhouse.Exception:
+		return errs.NewDB(int(e.Code), e.Message)
+	case *mysql.MySQLError:
+		return errs.NewDB(int(e.Number), e.Message)
+	case *pq.Error:
+		return errs.NewDB(pqErrorCode(e), e.Message)
+	case *network.OracleError:
+		return errs.NewDB(oraErrorCode(e), e.ErrMsg)
+	case *errs.Error:
+		return e
+	case sqlStateError:
+		if e.SQLState() == SQLStateSerializationFailure {
+			return errs.NewDB(ErrCodeSerializationFailure, err.Error())
+		}
+		return errs.NewDB(errs.ErrClientNet, err.Error())
+	case nil:
+		return nil
+	default:
+		return errs.NewDB(errs.ErrClientNet, err.Error())
+	}
+}
+
+// query 执行查询语句，timer 不为 nil 时为流式查询的语句超时定时器，语句执行完成后停止
+func (c *client) query(ctx context.Context,
+	query string, args []interface{}, next NextFunc, timer *time.Timer) (err error) {
 	var rows *sql.Rows
 
 	if c.tx != nil {
@@ -200,6 +348,13 @@ func (c *client) query(ctx context.Context, query string, args []interface{}, ne
 		rows, err = c.db.QueryContext(ctx, query, args...)
 	}
 
+	if timer != nil && !timer.Stop() { // 语句执行已超时
+		if err == nil {
+			rows.Close()
+		}
+		return context.DeadlineExceeded
+	}
+
 	if err != nil {
 		return
 	}
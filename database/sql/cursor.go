@@ -0,0 +1,102 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/util"
+	"github.com/horm-database/orm/obj"
+)
+
+// initCursor 游标分页，确定排序列，并在 where 条件中加入游标位置条件，
+// 例如按 a 升序、b 降序排列时，条件为 (a > ?) OR (a = ? AND b < ?)
+func (q *Query) initCursor() error {
+	if q.Size <= 0 {
+		return errs.Newf(errs.ErrReqParamInvalid, "cursor pagination size must be greater than 0, size=%d", q.Size)
+	}
+
+	q.cursorOrders = obj.CursorOrders(q.Order)
+	if len(q.Order) == 0 {
+		q.Order = []string{obj.DefaultCursorOrder}
+	}
+
+	values, err := obj.CursorValues(q.Cursor, q.cursorOrders)
+	if err != nil || values == nil {
+		return err
+	}
+
+	where := make(map[string]interface{}, len(q.Where)+1)
+	for k, v := range q.Where {
+		where[k] = v
+	}
+
+	where[consts.OR+" #cursor"] = cursorCondition(q.cursorOrders, values)
+	q.Where = where
+
+	return nil
+}
+
+// cursorCondition 游标位置条件
+func cursorCondition(orders []*util.Order, values []interface{}) []map[string]interface{} {
+	conds := make([]map[string]interface{}, len(orders))
+
+	for i, order := range orders {
+		cond := make(map[string]interface{}, i+1)
+		for j := 0; j < i; j++ {
+			cond[orders[j].Field] = values[j]
+		}
+
+		if order.Ascending {
+			cond[order.Field+" >"] = values[i]
+		} else {
+			cond[order.Field+" <"] = values[i]
+		}
+
+		conds[i] = cond
+	}
+
+	return conds
+}
+
+// findByCursor 游标分页查询，多查询一条用于判断是否还有下一页，不执行 count 查询，也不使用 OFFSET
+func (q *Query) findByCursor(ctx context.Context, statement *Statement) (interface{}, *proto.Detail, bool, error) {
+	statement.limit = q.Size + 1
+	statement.offset = 0
+
+	if q.SQL == "" {
+		q.SQL = statement.GetSQL()
+		q.Params = statement.params
+	}
+
+	rows, err := q.FindAll(ctx)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	rows, detail, err := obj.CursorResult(rows, q.Size, q.cursorOrders)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if len(rows) == 0 {
+		return nil, detail, true, nil
+	}
+
+	return rows, detail, false, nil
+}
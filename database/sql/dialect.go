@@ -0,0 +1,574 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/types"
+)
+
+// sqlDialect 数据库方言，屏蔽各数据库在标识符引号、占位符、分页、upsert、类型映射、行扫描上的差异。
+// 方言依赖包内语句与查询的内部状态（比如 NextRow 的行扫描），只在包内实现，不对外开放注册。
+type sqlDialect interface {
+	Quote(name string) string                                         // 标识符（表名、列名、别名）加引号
+	Table(name, alias string) string                                  // 带别名的表
+	Placeholder(index int) string                                     // 第 index 个参数的占位符，index 从 1 开始
+	LimitOffset(limit int, offset uint64) string                      // 分页语句
+	Update(table string) string                                       // update 语句前缀
+	Delete(table string) string                                       // delete 语句前缀
+	ModifyLimit() bool                                                // update、delete 是否支持 order by、limit
+	Insert(s *Statement) string                                       // insert 语句
+	Replace(s *Statement) string                                      // replace 语句，不支持 REPLACE 的数据库使用各自的 upsert 语法
+	Upsert(s *Statement) string                                       // upsert 语句，插入冲突时更新
+	NeedConflict() bool                                               // replace 是否必须指定冲突列
+	Lock(mode, wait string) string                                    // 行锁语句，不支持时返回空
+	LockWithLimit() bool                                              // 行锁能否与分页语句一起使用
+	IndexHint(hint string, indexes []string) string                   // 索引提示语句，不支持时返回空
+	OptimizerHint() bool                                              // 是否支持优化器提示 SELECT /*+ ... */
+	Returning() bool                                                  // 是否支持 RETURNING 返回修改后的数据
+	LastInsertID() bool                                               // 是否支持获取自增 id
+	TypeMap() map[string]types.Type                                   // 数据库类型与 golang 类型映射
+	NextRow(q *Query, rows *sql.Rows) (map[string]interface{}, error) // 扫描一行数据
+}
+
+// 索引提示
+const (
+	IndexForce  = "FORCE INDEX"
+	IndexUse    = "USE INDEX"
+	IndexIgnore = "IGNORE INDEX"
+)
+
+// 行锁
+const (
+	LockUpdate     = "update"      // 排他锁 FOR UPDATE
+	LockShare      = "share"       // 共享锁 FOR SHARE
+	LockSkipLocked = "skip_locked" // 跳过被锁定的行
+	LockNoWait     = "nowait"      // 行被锁定时立即返回失败，不等待
+)
+
+var dialects = map[int]sqlDialect{
+	consts.DBTypeMySQL:      &mysqlDialect{},
+	consts.DBTypeClickHouse: &clickhouseDialect{},
+	consts.DBTypePostgreSQL: &postgresDialect{},
+	consts.DBTypeSQLite:     &sqliteDialect{},
+	consts.DBTypeOracle:     &oracleDialect{},
+}
+
+// getDialect 获取数据库方言，没有对应方言的数据库使用 mysql 方言
+func getDialect(dbType int) sqlDialect {
+	if dialect, ok := dialects[dbType]; ok {
+		return dialect
+	}
+
+	return dialects[consts.DBTypeMySQL]
+}
+
+// rebind 将 ? 占位符转换为方言对应的占位符，忽略引号内的 ?
+func rebind(dialect sqlDialect, sql string) string {
+	if dialect.Placeholder(1) == "?" || strings.IndexByte(sql, '?') == -1 {
+		return sql
+	}
+
+	var n int
+	var quote byte
+	sqlBuilder := strings.Builder{}
+	sqlBuilder.Grow(len(sql) + 10)
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			sqlBuilder.WriteString(dialect.Placeholder(n))
+			continue
+		}
+
+		sqlBuilder.WriteByte(c)
+	}
+
+	return sqlBuilder.String()
+}
+
+// mysqlDialect mysql 方言，其他方言在此基础上覆盖差异部分
+type mysqlDialect struct{}
+
+func (d *mysqlDialect) Quote(name string) string {
+	return "`" + name + "`"
+}
+
+func (d *mysqlDialect) Table(name, alias string) string {
+	return d.Quote(name) + " AS " + d.Quote(alias)
+}
+
+func (d *mysqlDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (d *mysqlDialect) LimitOffset(limit int, offset uint64) string {
+	var ret string
+	if limit > 0 {
+		ret = fmt.Sprint(" LIMIT ", limit)
+	}
+	if offset > 0 {
+		ret += fmt.Sprint(" OFFSET ", offset)
+	}
+	return ret
+}
+
+func (d *mysqlDialect) Update(table string) string {
+	return "UPDATE " + d.Quote(table) + " SET "
+}
+
+func (d *mysqlDialect) Delete(table string) string {
+	return "DELETE FROM " + d.Quote(table) + " "
+}
+
+func (d *mysqlDialect) ModifyLimit() bool {
+	return true
+}
+
+func (d *mysqlDialect) Insert(s *Statement) string {
+	return fmt.Sprint("INSERT INTO ", s.quote(s.table), " ", s.set)
+}
+
+func (d *mysqlDialect) Replace(s *Statement) string {
+	return fmt.Sprint("REPLACE INTO ", d.Quote(s.table), " ", s.set)
+}
+
+// Upsert INSERT ... ON DUPLICATE KEY UPDATE，没有需要更新的列时将第一列更新为原值，冲突时不修改数据。
+// 不使用 INSERT IGNORE，它会把数据截断、非空约束等错误也降级为警告。
+func (d *mysqlDialect) Upsert(s *Statement) string {
+	set := s.upsertSet(func(column string) string {
+		return "VALUES(" + d.Quote(column) + ")"
+	})
+
+	if set == "" && len(s.columns) > 0 {
+		column := s.quote(strings.TrimSpace(s.columns[0]))
+		set = column + "=" + column
+	}
+
+	return fmt.Sprint("INSERT INTO ", d.Quote(s.table), " ", s.set, " ON DUPLICATE KEY UPDATE ", set)
+}
+
+func (d *mysqlDialect) NeedConflict() bool {
+	return false
+}
+
+// Lock FOR UPDATE / FOR SHARE [SKIP LOCKED | NOWAIT]，FOR SHARE 需要 mysql 8.0 以上
+func (d *mysqlDialect) Lock(mode, wait string) string {
+	var ret string
+
+	switch mode {
+	case LockUpdate:
+		ret = "FOR UPDATE"
+	case LockShare:
+		ret = "FOR SHARE"
+	default:
+		return ""
+	}
+
+	switch wait {
+	case LockSkipLocked:
+		ret += " SKIP LOCKED"
+	case LockNoWait:
+		ret += " NOWAIT"
+	}
+
+	return ret
+}
+
+func (d *mysqlDialect) LockWithLimit() bool {
+	return true
+}
+
+// IndexHint FORCE INDEX (`a`,`b`) / USE INDEX (...) / IGNORE INDEX (...)
+func (d *mysqlDialect) IndexHint(hint string, indexes []string) string {
+	if len(indexes) == 0 {
+		return ""
+	}
+
+	ret := strings.Builder{}
+	ret.WriteString(hint)
+	ret.WriteString(" (")
+	for k, index := range indexes {
+		if k > 0 {
+			ret.WriteString(",")
+		}
+		ret.WriteString(d.Quote(strings.TrimSpace(index)))
+	}
+	ret.WriteString(")")
+
+	return ret.String()
+}
+
+func (d *mysqlDialect) OptimizerHint() bool {
+	return true
+}
+
+func (d *mysqlDialect) Returning() bool {
+	return false
+}
+
+func (d *mysqlDialect) LastInsertID() bool {
+	return true
+}
+
+func (d *mysqlDialect) TypeMap() map[string]types.Type {
+	return MySQLTypeMap
+}
+
+func (d *mysqlDialect) NextRow(q *Query, rows *sql.Rows) (map[string]interface{}, error) {
+	return q.nextRow(rows)
+}
+
+// clickhouseDialect clickhouse 方言，update、delete 通过 ALTER TABLE 实现
+type clickhouseDialect struct {
+	mysqlDialect
+}
+
+func (d *clickhouseDialect) Update(table string) string {
+	return "ALTER TABLE " + d.Quote(table) + " UPDATE "
+}
+
+func (d *clickhouseDialect) Delete(table string) string {
+	return "ALTER TABLE " + d.Quote(table) + " DELETE "
+}
+
+func (d *clickhouseDialect) ModifyLimit() bool {
+	return false
+}
+
+func (d *clickhouseDialect) Lock(string, string) string {
+	return ""
+}
+
+func (d *clickhouseDialect) IndexHint(string, []string) string {
+	return ""
+}
+
+func (d *clickhouseDialect) OptimizerHint() bool {
+	return false
+}
+
+func (d *clickhouseDialect) LastInsertID() bool {
+	return false
+}
+
+func (d *clickhouseDialect) TypeMap() map[string]types.Type {
+	return ClickHouseTypeMap
+}
+
+func (d *clickhouseDialect) NextRow(q *Query, rows *sql.Rows) (map[string]interface{}, error) {
+	return q.nextRowCK(rows)
+}
+
+// postgresDialect postgresql 方言，双引号标识符，$n 占位符，replace 使用 ON CONFLICT 实现
+type postgresDialect struct {
+	mysqlDialect
+}
+
+func (d *postgresDialect) Quote(name string) string {
+	return `"` + name + `"`
+}
+
+func (d *postgresDialect) Table(name, alias string) string {
+	return d.Quote(name) + " AS " + d.Quote(alias)
+}
+
+func (d *postgresDialect) Placeholder(index int) string {
+	return "$" + strconv.Itoa(index)
+}
+
+func (d *postgresDialect) Update(table string) string {
+	return "UPDATE " + d.Quote(table) + " SET "
+}
+
+func (d *postgresDialect) Delete(table string) string {
+	return "DELETE FROM " + d.Quote(table) + " "
+}
+
+func (d *postgresDialect) ModifyLimit() bool {
+	return false
+}
+
+func (d *postgresDialect) Replace(s *Statement) string {
+	return fmt.Sprint("INSERT INTO ", d.Quote(s.table), " ", s.set, s.onConflictSQL())
+}
+
+func (d *postgresDialect) Upsert(s *Statement) string {
+	return fmt.Sprint("INSERT INTO ", d.Quote(s.table), " ", s.set, s.onConflictSQL())
+}
+
+func (d *postgresDialect) NeedConflict() bool {
+	return true
+}
+
+func (d *postgresDialect) IndexHint(string, []string) string {
+	return ""
+}
+
+// OptimizerHint postgresql 不支持优化器提示，/*+ ... */ 只是普通注释
+func (d *postgresDialect) OptimizerHint() bool {
+	return false
+}
+
+func (d *postgresDialect) Returning() bool {
+	return true
+}
+
+func (d *postgresDialect) LastInsertID() bool {
+	return false
+}
+
+func (d *postgresDialect) TypeMap() map[string]types.Type {
+	return PostgreSQLTypeMap
+}
+
+// sqliteDialect sqlite 方言，replace 使用 INSERT OR REPLACE，指定冲突列时使用 ON CONFLICT
+type sqliteDialect struct {
+	mysqlDialect
+}
+
+func (d *sqliteDialect) Quote(name string) string {
+	return `"` + name + `"`
+}
+
+func (d *sqliteDialect) Table(name, alias string) string {
+	return d.Quote(name) + " AS " + d.Quote(alias)
+}
+
+func (d *sqliteDialect) Update(table string) string {
+	return "UPDATE " + d.Quote(table) + " SET "
+}
+
+func (d *sqliteDialect) Delete(table string) string {
+	return "DELETE FROM " + d.Quote(table) + " "
+}
+
+func (d *sqliteDialect) ModifyLimit() bool {
+	return false
+}
+
+func (d *sqliteDialect) LimitOffset(limit int, offset uint64) string {
+	if offset > 0 && limit <= 0 { // sqlite OFFSET 必须与 LIMIT 一起使用，LIMIT -1 表示不限制条数
+		return fmt.Sprint(" LIMIT -1 OFFSET ", offset)
+	}
+	return d.mysqlDialect.LimitOffset(limit, offset)
+}
+
+func (d *sqliteDialect) Replace(s *Statement) string {
+	if s.conflictTarget != "" {
+		return fmt.Sprint("INSERT INTO ", d.Quote(s.table), " ", s.set, s.onConflictSQL())
+	}
+
+	return fmt.Sprint("INSERT OR REPLACE INTO ", d.Quote(s.table), " ", s.set)
+}
+
+func (d *sqliteDialect) Upsert(s *Statement) string {
+	return fmt.Sprint("INSERT INTO ", d.Quote(s.table), " ", s.set, s.onConflictSQL())
+}
+
+func (d *sqliteDialect) Lock(string, string) string {
+	return "" // sqlite 锁的粒度是整个库，不支持行锁
+}
+
+// IndexHint sqlite 只支持通过 INDEXED BY 强制使用一个索引
+func (d *sqliteDialect) IndexHint(hint string, indexes []string) string {
+	if hint != IndexForce || len(indexes) != 1 {
+		return ""
+	}
+	return "INDEXED BY " + d.Quote(strings.TrimSpace(indexes[0]))
+}
+
+func (d *sqliteDialect) OptimizerHint() bool {
+	return false
+}
+
+func (d *sqliteDialect) Returning() bool {
+	return true
+}
+
+func (d *sqliteDialect) TypeMap() map[string]types.Type {
+	return SQLiteTypeMap
+}
+
+// oracleDialect oracle 方言，:n 绑定变量，OFFSET/FETCH 分页，replace 使用 MERGE INTO 实现。
+// oracle 加引号的标识符区分大小写，而未加引号的标识符统一转为大写，所以普通标识符不加引号。
+type oracleDialect struct {
+	mysqlDialect
+}
+
+func (d *oracleDialect) Quote(name string) string {
+	return name
+}
+
+func (d *oracleDialect) Table(name, alias string) string {
+	return name + " " + alias // oracle 表别名不支持 AS
+}
+
+func (d *oracleDialect) Placeholder(index int) string {
+	return ":" + strconv.Itoa(index)
+}
+
+func (d *oracleDialect) LimitOffset(limit int, offset uint64) string {
+	var ret string
+	if offset > 0 {
+		ret = fmt.Sprint(" OFFSET ", offset, " ROWS")
+	}
+	if limit > 0 {
+		ret += fmt.Sprint(" FETCH NEXT ", limit, " ROWS ONLY")
+	}
+	return ret
+}
+
+func (d *oracleDialect) Update(table string) string {
+	return "UPDATE " + table + " SET "
+}
+
+func (d *oracleDialect) Delete(table string) string {
+	return "DELETE FROM " + table + " "
+}
+
+func (d *oracleDialect) ModifyLimit() bool {
+	return false
+}
+
+// Insert oracle 23c 之前不支持 VALUES (...),(...) 多行插入，多行时使用
+// INSERT ALL INTO t (a, b) VALUES (?, ?) INTO t (a, b) VALUES (?, ?) SELECT 1 FROM DUAL
+func (d *oracleDialect) Insert(s *Statement) string {
+	l := len(s.columns)
+	if l == 0 || len(s.params) <= l {
+		return d.mysqlDialect.Insert(s)
+	}
+
+	into := fmt.Sprint(" INTO ", s.table, " (", strings.Join(s.columns, ", "), ") VALUES (",
+		strings.Repeat("?, ", l-1), "?)")
+
+	sqlBuilder := strings.Builder{}
+	sqlBuilder.WriteString("INSERT ALL")
+	for i := 0; i < len(s.params)/l; i++ {
+		sqlBuilder.WriteString(into)
+	}
+	sqlBuilder.WriteString(" SELECT 1 FROM DUAL")
+
+	return sqlBuilder.String()
+}
+
+// Upsert 与 replace 一样使用 MERGE INTO，冲突时只更新指定列
+func (d *oracleDialect) Upsert(s *Statement) string {
+	return d.Replace(s)
+}
+
+// Replace MERGE INTO t T USING (SELECT ? a, ? b FROM DUAL UNION ALL ...) S ON (T.k = S.k)
+// WHEN MATCHED THEN UPDATE SET T.b = S.b WHEN NOT MATCHED THEN INSERT (a, b) VALUES (S.a, S.b)
+func (d *oracleDialect) Replace(s *Statement) string {
+	l := len(s.columns)
+	if l == 0 {
+		return ""
+	}
+
+	sqlBuilder := strings.Builder{}
+	sqlBuilder.WriteString("MERGE INTO ")
+	sqlBuilder.WriteString(s.table)
+	sqlBuilder.WriteString(" T USING (")
+
+	for i := 0; i < len(s.params)/l; i++ {
+		if i > 0 {
+			sqlBuilder.WriteString(" UNION ALL ")
+		}
+
+		sqlBuilder.WriteString("SELECT ")
+		for k, column := range s.columns {
+			if k > 0 {
+				sqlBuilder.WriteString(", ")
+			}
+			sqlBuilder.WriteString("? ")
+			sqlBuilder.WriteString(column)
+		}
+		sqlBuilder.WriteString(" FROM DUAL")
+	}
+
+	sqlBuilder.WriteString(") S ON (")
+	for k, column := range s.conflictColumns {
+		if k > 0 {
+			sqlBuilder.WriteString(" AND ")
+		}
+		column = strings.TrimSpace(column)
+		sqlBuilder.WriteString("T.")
+		sqlBuilder.WriteString(column)
+		sqlBuilder.WriteString(" = S.")
+		sqlBuilder.WriteString(column)
+	}
+	sqlBuilder.WriteString(")")
+
+	set := s.upsertSet(func(column string) string { // oracle 不允许更新 ON 子句中的列
+		return "S." + column
+	})
+
+	if set != "" {
+		sqlBuilder.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		sqlBuilder.WriteString(set)
+	}
+
+	sqlBuilder.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	sqlBuilder.WriteString(strings.Join(s.columns, ", "))
+	sqlBuilder.WriteString(") VALUES (S.")
+	sqlBuilder.WriteString(strings.Join(s.columns, ", S."))
+	sqlBuilder.WriteString(")")
+
+	return sqlBuilder.String()
+}
+
+// Lock oracle 不支持 FOR SHARE
+func (d *oracleDialect) Lock(mode, wait string) string {
+	if mode == LockShare {
+		return ""
+	}
+	return d.mysqlDialect.Lock(mode, wait)
+}
+
+// LockWithLimit oracle 的 FOR UPDATE 不能与 OFFSET/FETCH 一起使用（ORA-02014）
+func (d *oracleDialect) LockWithLimit() bool {
+	return false
+}
+
+// IndexHint oracle 没有索引提示语法，不生成任何语句，需要通过优化器提示 Hint("INDEX(t idx)") 指定索引
+func (d *oracleDialect) IndexHint(string, []string) string {
+	return ""
+}
+
+func (d *oracleDialect) NeedConflict() bool {
+	return true
+}
+
+func (d *oracleDialect) LastInsertID() bool {
+	return false
+}
+
+func (d *oracleDialect) TypeMap() map[string]types.Type {
+	return OracleTypeMap
+}
@@ -0,0 +1,372 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/util"
+	"github.com/horm-database/orm/obj"
+)
+
+// dryRun 试运行查询，返回生成的语句
+func dryRun(t *testing.T, dbType int, q *Query) []*obj.Statement {
+	t.Helper()
+
+	q.Addr = &util.DBAddress{Type: dbType}
+
+	statements, err := q.DryRun(context.Background())
+	if err != nil {
+		t.Fatalf("%s dry run error: %v", consts.DBTypeDesc[dbType], err)
+	}
+
+	return statements
+}
+
+// checkSQL 校验试运行生成的唯一一条语句及其参数
+func checkSQL(t *testing.T, dbType int, q *Query, want string, wantParams ...interface{}) {
+	t.Helper()
+
+	statements := dryRun(t, dbType, q)
+	if len(statements) != 1 {
+		t.Fatalf("%s statements count = %d, want 1", consts.DBTypeDesc[dbType], len(statements))
+	}
+
+	if got := statements[0].Query; got != want {
+		t.Errorf("%s sql\n got: %s\nwant: %s", consts.DBTypeDesc[dbType], got, want)
+	}
+
+	if got := statements[0].Params; !reflect.DeepEqual(got, wantParams) {
+		t.Errorf("%s params = %v, want %v", consts.DBTypeDesc[dbType], got, wantParams)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	const sql = "SELECT * FROM t WHERE a = ? AND b = '?' AND c = \"?\" AND d IN (?, ?)"
+
+	cases := map[int]string{
+		consts.DBTypeMySQL:      sql,
+		consts.DBTypeClickHouse: sql,
+		consts.DBTypeSQLite:     sql,
+		consts.DBTypePostgreSQL: "SELECT * FROM t WHERE a = $1 AND b = '?' AND c = \"?\" AND d IN ($2, $3)",
+		consts.DBTypeOracle:     "SELECT * FROM t WHERE a = :1 AND b = '?' AND c = \"?\" AND d IN (:2, :3)",
+	}
+
+	for dbType, want := range cases {
+		if got := rebind(getDialect(dbType), sql); got != want {
+			t.Errorf("%s rebind\n got: %s\nwant: %s", consts.DBTypeDesc[dbType], got, want)
+		}
+	}
+}
+
+func TestLimitOffset(t *testing.T) {
+	cases := []struct {
+		dbType int
+		limit  int
+		offset uint64
+		want   string
+	}{
+		{consts.DBTypeMySQL, 10, 0, " LIMIT 10"},
+		{consts.DBTypeMySQL, 10, 20, " LIMIT 10 OFFSET 20"},
+		{consts.DBTypePostgreSQL, 10, 20, " LIMIT 10 OFFSET 20"},
+		{consts.DBTypePostgreSQL, 0, 20, " OFFSET 20"},
+		{consts.DBTypeSQLite, 10, 20, " LIMIT 10 OFFSET 20"},
+		{consts.DBTypeSQLite, 0, 20, " LIMIT -1 OFFSET 20"},
+		{consts.DBTypeOracle, 10, 0, " FETCH NEXT 10 ROWS ONLY"},
+		{consts.DBTypeOracle, 10, 20, " OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{consts.DBTypeOracle, 0, 20, " OFFSET 20 ROWS"},
+	}
+
+	for _, c := range cases {
+		if got := getDialect(c.dbType).LimitOffset(c.limit, c.offset); got != c.want {
+			t.Errorf("%s LimitOffset(%d, %d) = %q, want %q",
+				consts.DBTypeDesc[c.dbType], c.limit, c.offset, got, c.want)
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	cases := map[int]string{
+		consts.DBTypeMySQL: " SELECT  `id` , `name`  FROM `user` WHERE  `age` > ?  " +
+			"ORDER BY id DESC LIMIT 10 OFFSET 20",
+		consts.DBTypePostgreSQL: ` SELECT  "id" , "name"  FROM "user" WHERE  "age" > $1  ` +
+			"ORDER BY id DESC LIMIT 10 OFFSET 20",
+		consts.DBTypeSQLite: ` SELECT  "id" , "name"  FROM "user" WHERE  "age" > ?  ` +
+			"ORDER BY id DESC LIMIT 10 OFFSET 20",
+		consts.DBTypeOracle: " SELECT  id , name  FROM user WHERE  age > :1  " +
+			"ORDER BY id DESC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+	}
+
+	for dbType, want := range cases {
+		q := &Query{
+			OP:     consts.OpFindAll,
+			Table:  "user",
+			Column: []string{"id", "name"},
+			Where:  map[string]interface{}{"age >": 18},
+			Order:  []string{"-id"},
+			Size:   10,
+			From:   20,
+		}
+
+		checkSQL(t, dbType, q, want, 18)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	cases := map[int]string{
+		consts.DBTypeMySQL:      "INSERT INTO `user` ( `id` , `name` ) values (?,?),(?,?)",
+		consts.DBTypePostgreSQL: `INSERT INTO "user" ( "id" , "name" ) values ($1,$2),($3,$4)`,
+		consts.DBTypeSQLite:     `INSERT INTO "user" ( "id" , "name" ) values (?,?),(?,?)`,
+		consts.DBTypeOracle: "INSERT ALL INTO user (id, name) VALUES (:1, :2) " +
+			"INTO user (id, name) VALUES (:3, :4) SELECT 1 FROM DUAL",
+	}
+
+	for dbType, want := range cases {
+		q := &Query{
+			OP:    consts.OpInsert,
+			Table: "user",
+			Datas: []map[string]interface{}{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}},
+		}
+
+		checkSQL(t, dbType, q, want, 1, "a", 2, "b")
+	}
+
+	q := &Query{OP: consts.OpInsert, Table: "user", Data: map[string]interface{}{"id": 1, "name": "a"}}
+	checkSQL(t, consts.DBTypeOracle, q, "INSERT INTO user ( id , name ) values (:1,:2)", 1, "a")
+}
+
+func TestUpsert(t *testing.T) {
+	cases := map[int]string{
+		consts.DBTypeMySQL: "INSERT INTO `user` ( `id` , `name` , `score` ) values (?,?,?),(?,?,?) " +
+			"ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),score=score+1",
+		consts.DBTypePostgreSQL: `INSERT INTO "user" ( "id" , "name" , "score" ) values ($1,$2,$3),($4,$5,$6) ` +
+			`ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name",score=score+1`,
+		consts.DBTypeSQLite: `INSERT INTO "user" ( "id" , "name" , "score" ) values (?,?,?),(?,?,?) ` +
+			`ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name",score=score+1`,
+		consts.DBTypeOracle: "MERGE INTO user T USING (" +
+			"SELECT :1 id, :2 name, :3 score FROM DUAL UNION ALL SELECT :4 id, :5 name, :6 score FROM DUAL" +
+			") S ON (T.id = S.id) WHEN MATCHED THEN UPDATE SET name=S.name,score=score+1 " +
+			"WHEN NOT MATCHED THEN INSERT (id, name, score) VALUES (S.id, S.name, S.score)",
+	}
+
+	for dbType, want := range cases {
+		q := &Query{
+			OP:    consts.OpInsert,
+			Table: "user",
+			Datas: []map[string]interface{}{
+				{"id": 1, "name": "a", "score": 1},
+				{"id": 2, "name": "b", "score": 1},
+			},
+			Upsert:        true,
+			OnConflict:    []string{"id"},
+			UpdateColumns: []string{"name", "score=score+1"},
+		}
+
+		checkSQL(t, dbType, q, want, 1, "a", 1, 2, "b", 1)
+	}
+}
+
+func TestUpsertAll(t *testing.T) {
+	cases := map[int]string{
+		consts.DBTypeMySQL: "INSERT INTO `user` ( `id` , `name` ) values (?,?) " +
+			"ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)",
+		consts.DBTypePostgreSQL: `INSERT INTO "user" ( "id" , "name" ) values ($1,$2) ` +
+			`ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`,
+		consts.DBTypeOracle: "MERGE INTO user T USING (SELECT :1 id, :2 name FROM DUAL) S ON (T.id = S.id) " +
+			"WHEN MATCHED THEN UPDATE SET name=S.name " +
+			"WHEN NOT MATCHED THEN INSERT (id, name) VALUES (S.id, S.name)",
+	}
+
+	for dbType, want := range cases {
+		q := &Query{
+			OP:         consts.OpInsert,
+			Table:      "user",
+			Data:       map[string]interface{}{"id": 1, "name": "a"},
+			Upsert:     true,
+			OnConflict: []string{"id"},
+		}
+
+		checkSQL(t, dbType, q, want, 1, "a")
+	}
+
+	// 只有冲突列时，mysql 冲突时不修改数据，postgresql 不做处理
+	nothing := map[int]string{
+		consts.DBTypeMySQL:      "INSERT INTO `user` ( `id` ) values (?) ON DUPLICATE KEY UPDATE `id`=`id`",
+		consts.DBTypePostgreSQL: `INSERT INTO "user" ( "id" ) values ($1) ON CONFLICT ("id") DO NOTHING`,
+	}
+
+	for dbType, want := range nothing {
+		q := &Query{
+			OP:         consts.OpInsert,
+			Table:      "user",
+			Data:       map[string]interface{}{"id": 1},
+			Upsert:     true,
+			OnConflict: []string{"id"},
+		}
+
+		checkSQL(t, dbType, q, want, 1)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	cases := map[int]string{
+		consts.DBTypeMySQL: "REPLACE INTO `user` ( `id` , `name` ) values (?,?)",
+		consts.DBTypePostgreSQL: `INSERT INTO "user" ( "id" , "name" ) values ($1,$2) ` +
+			`ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`,
+		consts.DBTypeSQLite: `INSERT INTO "user" ( "id" , "name" ) values (?,?) ` +
+			`ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`,
+		consts.DBTypeOracle: "MERGE INTO user T USING (SELECT :1 id, :2 name FROM DUAL) S ON (T.id = S.id) " +
+			"WHEN MATCHED THEN UPDATE SET name=S.name " +
+			"WHEN NOT MATCHED THEN INSERT (id, name) VALUES (S.id, S.name)",
+	}
+
+	for dbType, want := range cases {
+		q := &Query{
+			OP:         consts.OpReplace,
+			Table:      "user",
+			Data:       map[string]interface{}{"id": 1, "name": "a"},
+			OnConflict: []string{"id"},
+		}
+
+		checkSQL(t, dbType, q, want, 1, "a")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	cursor, err := obj.EncodeCursor([]interface{}{100})
+	if err != nil {
+		t.Fatalf("encode cursor error: %v", err)
+	}
+
+	if cursor != "WzEwMF0" {
+		t.Errorf("cursor = %s, want WzEwMF0", cursor)
+	}
+
+	values, err := obj.DecodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("decode cursor error: %v", err)
+	}
+
+	if len(values) != 1 || values[0] != json.Number("100") { // 数字解码为 json.Number，避免大整数丢失精度
+		t.Fatalf("decode cursor = %v, want [100]", values)
+	}
+
+	cases := map[int]string{
+		consts.DBTypeMySQL:      " SELECT * FROM `user` WHERE   (  ( `id` < ? ) )  ORDER BY id DESC LIMIT 3",
+		consts.DBTypePostgreSQL: ` SELECT * FROM "user" WHERE   (  ( "id" < $1 ) )  ORDER BY id DESC LIMIT 3`,
+		consts.DBTypeOracle:     " SELECT * FROM user WHERE   (  ( id < :1 ) )  ORDER BY id DESC FETCH NEXT 3 ROWS ONLY",
+	}
+
+	for dbType, want := range cases {
+		q := &Query{
+			OP:         consts.OpFindAll,
+			Table:      "user",
+			Order:      []string{"-id"},
+			Size:       2,
+			Cursor:     cursor,
+			cursorMode: true,
+		}
+
+		checkSQL(t, dbType, q, want, values[0])
+	}
+}
+
+func TestLock(t *testing.T) {
+	cases := map[int]string{
+		consts.DBTypeMySQL:      " SELECT * FROM `user` WHERE   `id` = ?  LIMIT 1 FOR UPDATE SKIP LOCKED",
+		consts.DBTypePostgreSQL: ` SELECT * FROM "user" WHERE   "id" = $1  LIMIT 1 FOR UPDATE SKIP LOCKED`,
+	}
+
+	for dbType, want := range cases {
+		q := &Query{
+			OP:        consts.OpFind,
+			Table:     "user",
+			Where:     map[string]interface{}{"id": 1},
+			Lock:      LockUpdate,
+			LockWait:  LockSkipLocked,
+			TransInfo: &obj.TransInfo{},
+		}
+
+		checkSQL(t, dbType, q, want, 1)
+	}
+
+	// oracle 的 FOR UPDATE 不能与 FETCH 一起使用
+	q := &Query{
+		OP:        consts.OpFindAll,
+		Table:     "user",
+		Where:     map[string]interface{}{"id": 1},
+		Lock:      LockUpdate,
+		TransInfo: &obj.TransInfo{},
+	}
+
+	checkSQL(t, consts.DBTypeOracle, q, " SELECT * FROM user WHERE   id = :1  FOR UPDATE", 1)
+
+	for _, op := range []string{consts.OpFind, consts.OpFindAll} {
+		q = &Query{
+			OP:        op,
+			Table:     "user",
+			Where:     map[string]interface{}{"id": 1},
+			Size:      10,
+			Lock:      LockUpdate,
+			TransInfo: &obj.TransInfo{},
+			Addr:      &util.DBAddress{Type: consts.DBTypeOracle},
+		}
+
+		if _, err := q.DryRun(context.Background()); err == nil {
+			t.Errorf("oracle %s with lock and limit should return error", op)
+		}
+	}
+}
+
+func TestHint(t *testing.T) {
+	q := &Query{
+		OP:             consts.OpFindAll,
+		Table:          "user",
+		IndexHints:     map[string][]string{IndexForce: {"idx_age"}},
+		OptimizerHints: []string{"MAX_EXECUTION_TIME(1000)"},
+	}
+
+	checkSQL(t, consts.DBTypeMySQL, q,
+		" SELECT /*+ MAX_EXECUTION_TIME(1000) */ * FROM `user` FORCE INDEX (`idx_age`)")
+
+	q = &Query{OP: consts.OpFindAll, Table: "user", OptimizerHints: []string{"INDEX(user idx_age)"}}
+	checkSQL(t, consts.DBTypeOracle, q, " SELECT /*+ INDEX(user idx_age) */ * FROM user")
+
+	errCases := []struct {
+		dbType int
+		hint   string
+	}{
+		{consts.DBTypeMySQL, "BKA(t1) */ DROP TABLE t1; /*"}, // 提示不能提前结束注释
+		{consts.DBTypePostgreSQL, "SeqScan(user)"},           // 不支持优化器提示的数据库
+		{consts.DBTypeSQLite, "MAX_EXECUTION_TIME(1000)"},
+	}
+
+	for _, c := range errCases {
+		q = &Query{
+			OP:             consts.OpFindAll,
+			Table:          "user",
+			OptimizerHints: []string{c.hint},
+			Addr:           &util.DBAddress{Type: c.dbType},
+		}
+
+		if _, err := q.DryRun(context.Background()); err == nil {
+			t.Errorf("%s optimizer hint [%s] should return error", consts.DBTypeDesc[c.dbType], c.hint)
+		}
+	}
+}
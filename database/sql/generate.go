@@ -22,105 +22,167 @@ import (
 )
 
 func (s *Statement) GetSQL() string {
+	var sql string
+
 	switch s.op {
 	case consts.OpInsert:
-		return s.InsertSQL()
+		sql = s.InsertSQL()
 	case consts.OpReplace:
-		return s.ReplaceSQL()
+		sql = s.ReplaceSQL()
 	case consts.OpUpdate:
-		return s.UpdateSQL()
+		sql = s.UpdateSQL()
 	case consts.OpDelete:
-		return s.DeleteSQL()
+		sql = s.DeleteSQL()
 	case consts.OpFind, consts.OpFindAll:
-		return s.FindSQL()
+		sql = s.FindSQL()
 	case consts.OpCount:
-		return s.CountSQL()
+		sql = s.CountSQL()
 	}
 
-	return ""
+	return rebind(s.dialect(), sql)
 }
 
-// InsertSQL 创建 insert 语句
+// InsertSQL 创建 insert 语句，upsert 时由方言生成冲突更新语句
 func (s *Statement) InsertSQL() string {
-	return fmt.Sprint("INSERT INTO `", s.table, "` ", s.set)
+	if s.upsert {
+		return s.dialect().Upsert(s) + s.returningSQL()
+	}
+
+	return s.dialect().Insert(s) + s.returningSQL()
 }
 
-// ReplaceSQL 创建 replace 语句
+// ReplaceSQL 创建 replace 语句，不支持 replace 的数据库由方言使用 upsert 语法实现
 func (s *Statement) ReplaceSQL() string {
-	return fmt.Sprint("REPLACE INTO `", s.table, "` ", s.set)
+	return s.dialect().Replace(s) + s.returningSQL()
 }
 
-// UpdateSQL 创建 update 语句
-func (s *Statement) UpdateSQL() string {
+// onConflictSQL postgresql、sqlite 冲突处理，冲突时更新指定列，没有需要更新的列时不做处理
+func (s *Statement) onConflictSQL() string {
 	sqlBuilder := strings.Builder{}
+	sqlBuilder.WriteString(" ON CONFLICT")
 
-	if s.dbType == consts.DBTypeClickHouse {
-		sqlBuilder.WriteString("ALTER TABLE `")
-		sqlBuilder.WriteString(s.table)
-		sqlBuilder.WriteString("` UPDATE ")
-		sqlBuilder.WriteString(s.set)
+	if s.conflictTarget != "" {
+		sqlBuilder.WriteString(" (")
+		sqlBuilder.WriteString(s.conflictTarget)
+		sqlBuilder.WriteString(")")
+	}
+
+	set := s.upsertSet(func(column string) string {
+		return "EXCLUDED." + s.quote(column)
+	})
+
+	if set == "" {
+		sqlBuilder.WriteString(" DO NOTHING")
 	} else {
-		sqlBuilder.WriteString("UPDATE `")
-		sqlBuilder.WriteString(s.table)
-		sqlBuilder.WriteString("` SET ")
-		sqlBuilder.WriteString(s.set)
+		sqlBuilder.WriteString(" DO UPDATE SET ")
+		sqlBuilder.WriteString(set)
 	}
-	if s.where != "" {
-		sqlBuilder.WriteString(" WHERE ")
-		sqlBuilder.WriteString(s.where)
+
+	return sqlBuilder.String()
+}
+
+// upsertSet 冲突时的更新语句，value 返回列对应的新值。未指定更新列时更新所有插入的列（冲突列除外），
+// 包含 = 的更新列为表达式，原样输出。
+func (s *Statement) upsertSet(value func(column string) string) string {
+	sqlBuilder := strings.Builder{}
+
+	columns := s.updateColumns
+	if len(columns) == 0 {
+		columns = s.columns
 	}
-	if s.GetOrder() != "" {
-		sqlBuilder.WriteString(" ORDER BY ")
-		sqlBuilder.WriteString(s.GetOrder())
+
+	var i int
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		if column == "" || (len(s.updateColumns) == 0 && inColumns(column, s.conflictColumns)) {
+			continue
+		}
+
+		if i > 0 {
+			sqlBuilder.WriteString(",")
+		}
+
+		if strings.Contains(column, "=") {
+			sqlBuilder.WriteString(column)
+		} else {
+			sqlBuilder.WriteString(s.quote(column))
+			sqlBuilder.WriteString("=")
+			sqlBuilder.WriteString(value(column))
+		}
+
+		i++
 	}
-	if s.limit > 0 {
-		sqlBuilder.WriteString(" LIMIT ")
-		sqlBuilder.WriteString(fmt.Sprint(s.limit))
+
+	return sqlBuilder.String()
+}
+
+// returningSQL postgresql 返回被修改的数据
+func (s *Statement) returningSQL() string {
+	if s.returning == "" {
+		return ""
 	}
-	if s.offset > 0 {
-		sqlBuilder.WriteString(" OFFSET ")
-		sqlBuilder.WriteString(fmt.Sprint(s.offset))
+
+	return " RETURNING " + s.returning
+}
+
+// UpdateSQL 创建 update 语句
+func (s *Statement) UpdateSQL() string {
+	sqlBuilder := strings.Builder{}
+	sqlBuilder.WriteString(s.dialect().Update(s.table))
+	sqlBuilder.WriteString(s.set)
+
+	if s.where != "" {
+		sqlBuilder.WriteString(" WHERE ")
+		sqlBuilder.WriteString(s.where)
 	}
+
+	s.modifyLimit(&sqlBuilder)
+	sqlBuilder.WriteString(s.returningSQL())
+
 	return sqlBuilder.String()
 }
 
 // DeleteSQL 创建 delete 语句
 func (s *Statement) DeleteSQL() string {
 	sqlBuilder := strings.Builder{}
-
-	if s.dbType == consts.DBTypeClickHouse {
-		sqlBuilder.WriteString("ALTER TABLE `")
-		sqlBuilder.WriteString(s.table)
-		sqlBuilder.WriteString("` DELETE ")
-	} else {
-		sqlBuilder.WriteString("DELETE FROM `")
-		sqlBuilder.WriteString(s.table)
-		sqlBuilder.WriteString("` ")
-	}
+	sqlBuilder.WriteString(s.dialect().Delete(s.table))
 
 	if s.where != "" {
 		sqlBuilder.WriteString(" WHERE ")
 		sqlBuilder.WriteString(s.where)
 	}
+
+	s.modifyLimit(&sqlBuilder)
+	sqlBuilder.WriteString(s.returningSQL())
+
+	return sqlBuilder.String()
+}
+
+// modifyLimit update、delete 的排序与条数限制，方言不支持时忽略
+func (s *Statement) modifyLimit(sqlBuilder *strings.Builder) {
+	if !s.dialect().ModifyLimit() {
+		return
+	}
+
 	if s.GetOrder() != "" {
 		sqlBuilder.WriteString(" ORDER BY ")
 		sqlBuilder.WriteString(s.GetOrder())
 	}
-	if s.limit > 0 {
-		sqlBuilder.WriteString(" LIMIT ")
-		sqlBuilder.WriteString(fmt.Sprint(s.limit))
-	}
-	if s.offset > 0 {
-		sqlBuilder.WriteString(" OFFSET ")
-		sqlBuilder.WriteString(fmt.Sprint(s.offset))
-	}
 
-	return sqlBuilder.String()
+	sqlBuilder.WriteString(s.dialect().LimitOffset(s.limit, s.offset))
 }
 
-// CountSQL 创建 count 语句
+// CountSQL 创建 count 语句，distinct 查询所有列或多列时，通过子查询统计去重后的条数
 func (s *Statement) CountSQL() (sql string) {
-	origin := s.selects
+	if s.distinct && (s.selects == "*" || s.selects == "" || strings.Contains(s.selects, ",")) {
+		sqlBuilder := strings.Builder{}
+		sqlBuilder.WriteString("SELECT count(*) FROM (")
+		sqlBuilder.WriteString(s.findSQL().String())
+		sqlBuilder.WriteString(") horm_distinct")
+		return sqlBuilder.String()
+	}
+
+	origin, distinct := s.selects, s.distinct
 
 	if s.selects == "*" || s.selects == "" {
 		s.selects = "count(*)"
@@ -140,8 +202,9 @@ func (s *Statement) CountSQL() (sql string) {
 		}
 	}
 
+	s.distinct = false // 去重已经在 count 中处理
 	sqlBuilder := s.findSQL()
-	s.selects = origin
+	s.selects, s.distinct = origin, distinct
 	return sqlBuilder.String()
 }
 
@@ -154,15 +217,7 @@ func (s *Statement) FindSQL() (sql string) {
 		sqlBuilder.WriteString(s.GetOrder())
 	}
 
-	if s.limit > 0 {
-		sqlBuilder.WriteString(" LIMIT ")
-		sqlBuilder.WriteString(fmt.Sprint(s.limit))
-	}
-
-	if s.offset > 0 {
-		sqlBuilder.WriteString(" OFFSET ")
-		sqlBuilder.WriteString(fmt.Sprint(s.offset))
-	}
+	sqlBuilder.WriteString(s.dialect().LimitOffset(s.limit, s.offset))
 
 	if s.forUpdate != "" {
 		sqlBuilder.WriteString(" ")
@@ -175,20 +230,25 @@ func (s *Statement) FindSQL() (sql string) {
 func (s *Statement) findSQL() *strings.Builder {
 	sqlBuilder := strings.Builder{}
 
+	sqlBuilder.WriteString(" SELECT ")
+
+	if s.optimizerHints != "" {
+		sqlBuilder.WriteString("/*+ ")
+		sqlBuilder.WriteString(s.optimizerHints)
+		sqlBuilder.WriteString(" */ ")
+	}
+
+	if s.distinct {
+		sqlBuilder.WriteString("DISTINCT ")
+	}
+
+	sqlBuilder.WriteString(s.selects)
+	sqlBuilder.WriteString(" FROM ")
+
 	if s.alias != "" {
-		sqlBuilder.WriteString(" SELECT ")
-		sqlBuilder.WriteString(s.selects)
-		sqlBuilder.WriteString(" FROM `")
-		sqlBuilder.WriteString(s.table)
-		sqlBuilder.WriteString("` AS `")
-		sqlBuilder.WriteString(s.alias)
-		sqlBuilder.WriteString("`")
+		sqlBuilder.WriteString(s.dialect().Table(s.table, s.alias))
 	} else {
-		sqlBuilder.WriteString(" SELECT ")
-		sqlBuilder.WriteString(s.selects)
-		sqlBuilder.WriteString(" FROM `")
-		sqlBuilder.WriteString(s.table)
-		sqlBuilder.WriteString("`")
+		sqlBuilder.WriteString(s.quote(s.table))
 	}
 
 	if s.indexHints != "" {
@@ -221,3 +281,12 @@ func (s *Statement) findSQL() *strings.Builder {
 
 	return &sqlBuilder
 }
+
+func inColumns(column string, columns []string) bool {
+	for _, v := range columns {
+		if strings.TrimSpace(v) == column {
+			return true
+		}
+	}
+	return false
+}
@@ -17,8 +17,10 @@ package sql
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/log"
 	"github.com/horm-database/common/proto"
 	"github.com/horm-database/common/proto/plugin"
@@ -55,8 +57,35 @@ type Query struct {
 	TblTable  *obj.TblTable
 	TransInfo *obj.TransInfo
 	TimeLog   *log.TimeLog
+	Receiver  interface{} // 结果接收者，为结构体数组指针时 FindAll 直接扫描到结构体中
+	Tag       string      // 直接扫描到结构体时，结构体字段与列名映射的 tag
 
-	client client.Client
+	// 冲突判断列、返回列、upsert
+	OnConflict    []string
+	Returning     []string
+	Upsert        bool
+	UpdateColumns []string
+
+	// 行锁
+	Lock     string
+	LockWait string
+
+	Distinct bool // 查询去重
+
+	// 索引提示、优化器提示
+	IndexHints     map[string][]string
+	OptimizerHints []string
+
+	// 游标分页
+	Cursor       string
+	cursorMode   bool
+	cursorOrders []*util.Order
+
+	client    client.Client
+	streaming bool // 流式查询，读超时只作用于语句的执行
+
+	dryRun     bool             // 试运行，只记录语句，不访问数据库
+	statements []*obj.Statement // 试运行记录的语句
 }
 
 func (q *Query) SetParams(req *plugin.Request,
@@ -81,11 +110,76 @@ func (q *Query) SetParams(req *plugin.Request,
 	q.DB = property.DB
 	q.Addr = addr
 	q.TblTable = property.Table
+	q.Receiver = property.Receiver
+	q.Tag = property.Tag
 
 	q.Shard = req.Tables
 	q.Join = req.Join
 
 	q.IfNotExists, _ = req.Params.GetBool("if_not_exists")
+	q.Cursor, q.cursorMode = req.Params.GetString("cursor")
+
+	var err error
+
+	q.OnConflict, _, err = req.Params.GetStringArray("on_conflict")
+	if err != nil {
+		return errs.Newf(errs.ErrReqParamInvalid, "params on_conflict is invalid: %v", err)
+	}
+
+	q.Returning, _, err = req.Params.GetStringArray("returning")
+	if err != nil {
+		return errs.Newf(errs.ErrReqParamInvalid, "params returning is invalid: %v", err)
+	}
+
+	q.Lock, _ = req.Params.GetString("lock")
+	q.LockWait, _ = req.Params.GetString("lock_wait")
+	if q.LockWait != "" && q.Lock == "" { // 只设置了等待方式，默认为排他锁
+		q.Lock = LockUpdate
+	}
+
+	q.Distinct, _ = req.Params.GetBool("distinct")
+
+	q.IndexHints = map[string][]string{}
+	for param, hint := range map[string]string{
+		"force_index":  IndexForce,
+		"use_index":    IndexUse,
+		"ignore_index": IndexIgnore,
+	} {
+		indexes, _, err := req.Params.GetStringArray(param)
+		if err != nil {
+			return errs.Newf(errs.ErrReqParamInvalid, "params %s is invalid: %v", param, err)
+		}
+
+		if len(indexes) > 0 {
+			q.IndexHints[hint] = indexes
+		}
+	}
+
+	q.OptimizerHints, _, err = req.Params.GetStringArray("optimizer_hints")
+	if err != nil {
+		return errs.Newf(errs.ErrReqParamInvalid, "params optimizer_hints is invalid: %v", err)
+	}
+
+	maxExecutionTime, _, err := req.Params.GetInt64("max_execution_time")
+	if err != nil {
+		return errs.Newf(errs.ErrReqParamInvalid, "params max_execution_time is invalid: %v", err)
+	}
+
+	if maxExecutionTime > 0 {
+		if q.Addr.Type != consts.DBTypeMySQL {
+			db, _ := consts.DBTypeDesc[q.Addr.Type]
+			return errs.Newf(errs.ErrReqParamInvalid, "%s does not support max_execution_time", db)
+		}
+
+		q.OptimizerHints = append(q.OptimizerHints, fmt.Sprintf("MAX_EXECUTION_TIME(%d)", maxExecutionTime))
+	}
+
+	q.Upsert, _ = req.Params.GetBool("upsert")
+	q.UpdateColumns, _, err = req.Params.GetStringArray("update_columns")
+	if err != nil {
+		return errs.Newf(errs.ErrReqParamInvalid, "params update_columns is invalid: %v", err)
+	}
+
 	return nil
 }
 
@@ -98,32 +192,19 @@ func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 		return result, nil, false, err
 	}
 
-	statement := &Statement{dbType: q.Addr.Type, op: q.OP}
-	statement.SetColumn(q.Column)
-	statement.SetTable(q.Table, q.Alias)
-	statement.Join(q.Join)
-
 	if len(q.Data) > 0 {
 		q.Datas = append(q.Datas, q.Data)
 	}
 
-	if q.OP == consts.OpInsert {
-		if q.Addr.Type == consts.DBTypeClickHouse {
-			_, _, err := q.InsertToCK(ctx, "", false, 0, q.Table, q.Datas)
-			return proto.ModRet{}, nil, false, err
-		} else {
-			statement.SetMaps(q.Datas)
-		}
-	} else if q.OP == consts.OpReplace {
-		statement.SetMaps(q.Datas)
-	} else if q.OP == consts.OpUpdate {
-		statement.UpdateMap(q.Data)
+	if q.OP == consts.OpInsert && !q.Upsert && q.Addr.Type == consts.DBTypeClickHouse {
+		_, _, err := q.InsertToCK(ctx, "", false, 0, q.Table, q.Datas)
+		return proto.ModRet{}, nil, false, err
 	}
 
-	statement.Where(q.Addr.Type, q.Where)
-	statement.Group(q.Group)
-	statement.Having(q.Addr.Type, q.Having)
-	statement.Order(q.Order)
+	statement, err := q.statement()
+	if err != nil {
+		return nil, nil, false, err
+	}
 
 	if q.OP == consts.OpInsert || q.OP == consts.OpReplace || q.OP == consts.OpUpdate || q.OP == consts.OpDelete {
 		if q.SQL == "" {
@@ -131,6 +212,15 @@ func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 			q.Params = statement.params
 		}
 
+		if len(q.Returning) > 0 { // 返回被修改的数据
+			dest, err := q.FindAll(ctx)
+			if err != nil {
+				return nil, nil, false, err
+			}
+
+			return dest, nil, len(dest) == 0, nil
+		}
+
 		rowsAffected, lastInsertID, err := q.execute(ctx)
 		if err != nil {
 			return nil, nil, false, err
@@ -165,10 +255,14 @@ func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 
 		return dest, nil, false, nil
 	} else if q.OP == consts.OpFindAll {
+		if q.cursorMode {
+			return q.findByCursor(ctx, statement)
+		}
+
 		var detail *proto.Detail
 
 		if q.Page > 0 {
-			q.CountSQL = statement.CountSQL()
+			q.CountSQL = rebind(q.dialect(), statement.CountSQL())
 			q.Params = statement.params
 
 			detail = &proto.Detail{Page: q.Page, Size: q.Size}
@@ -190,6 +284,19 @@ func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 			q.Params = statement.params
 		}
 
+		if receiver, ok := q.structReceiver(); ok {
+			n, err := q.FindAllStruct(ctx, receiver)
+			if err != nil {
+				return nil, nil, false, err
+			}
+
+			if n == 0 {
+				return nil, detail, true, nil
+			}
+
+			return q.Receiver, detail, false, nil
+		}
+
 		dest, err := q.FindAll(ctx)
 		if err != nil {
 			return nil, nil, false, err
@@ -220,6 +327,170 @@ func (q *Query) Query(ctx context.Context) (interface{}, *proto.Detail, bool, er
 	return nil, nil, false, nil
 }
 
+// statement 根据查询参数生成语句，Query 与 Stream 共用
+func (q *Query) statement() (*Statement, error) {
+	statement := &Statement{dbType: q.Addr.Type, op: q.OP}
+	statement.SetColumn(q.Column)
+	statement.SetTable(q.Table, q.Alias)
+	statement.Join(q.Join)
+	statement.Distinct(q.Distinct)
+
+	if q.OP == consts.OpInsert && q.Upsert {
+		if err := q.checkConflict("upsert"); err != nil {
+			return nil, err
+		}
+
+		statement.SetMaps(q.Datas)
+		statement.OnConflict(q.OnConflict)
+		statement.Upsert(q.UpdateColumns)
+	} else if q.OP == consts.OpInsert {
+		statement.SetMaps(q.Datas)
+	} else if q.OP == consts.OpReplace {
+		if err := q.checkConflict("replace"); err != nil {
+			return nil, err
+		}
+
+		statement.SetMaps(q.Datas)
+		statement.OnConflict(q.OnConflict)
+	} else if q.OP == consts.OpUpdate {
+		statement.UpdateMap(q.Data)
+	}
+
+	if len(q.Returning) > 0 {
+		if !q.dialect().Returning() {
+			db, _ := consts.DBTypeDesc[q.Addr.Type]
+			return nil, errs.Newf(errs.ErrReqParamInvalid, "%s does not support returning", db)
+		}
+
+		statement.Returning(q.Returning)
+	}
+
+	if q.OP == consts.OpFind || q.OP == consts.OpFindAll || q.OP == consts.OpCount {
+		if err := q.hint(statement); err != nil {
+			return nil, err
+		}
+	}
+
+	if q.Lock != "" {
+		if err := q.lock(statement); err != nil {
+			return nil, err
+		}
+	}
+
+	if q.OP == consts.OpFindAll && q.cursorMode {
+		if err := q.initCursor(); err != nil {
+			return nil, err
+		}
+	}
+
+	statement.Where(q.Addr.Type, q.Where)
+	statement.Group(q.Group)
+	statement.Having(q.Addr.Type, q.Having)
+	statement.Order(q.Order)
+
+	return statement, nil
+}
+
+// hint 查询的索引提示、优化器提示
+func (q *Query) hint(statement *Statement) error {
+	for _, hint := range []string{IndexForce, IndexUse, IndexIgnore} { // 固定顺序，保证生成的语句稳定
+		indexes, ok := q.IndexHints[hint]
+		if !ok {
+			continue
+		}
+
+		clause := q.dialect().IndexHint(hint, indexes)
+		if clause == "" {
+			db, _ := consts.DBTypeDesc[q.Addr.Type]
+			return errs.Newf(errs.ErrReqParamInvalid, "%s does not support index hint [%s %v]", db, hint, indexes)
+		}
+
+		statement.IndexHint(clause)
+	}
+
+	if len(q.OptimizerHints) == 0 {
+		return nil
+	}
+
+	if !q.dialect().OptimizerHint() {
+		db, _ := consts.DBTypeDesc[q.Addr.Type]
+		return errs.Newf(errs.ErrReqParamInvalid, "%s does not support optimizer hint %v", db, q.OptimizerHints)
+	}
+
+	for _, hint := range q.OptimizerHints {
+		if strings.Contains(hint, "*/") { // 提示会被拼接到注释 /*+ ... */ 中，不能提前结束注释
+			return errs.Newf(errs.ErrReqParamInvalid, "optimizer hint [%s] can not contain */", hint)
+		}
+	}
+
+	statement.OptimizerHint(q.OptimizerHints)
+	return nil
+}
+
+// lock 查询加行锁，行锁只在事务内有效，所以必须在事务中使用，只有 find、find_all 支持加锁
+func (q *Query) lock(statement *Statement) error {
+	if q.OP != consts.OpFind && q.OP != consts.OpFindAll {
+		return errs.Newf(errs.ErrReqParamInvalid, "lock [%s] only support find and find_all, op=[%s]", q.Lock, q.OP)
+	}
+
+	if q.TransInfo == nil {
+		return errs.Newf(errs.ErrReqParamInvalid, "lock [%s] must be used in transaction", q.Lock)
+	}
+
+	db, _ := consts.DBTypeDesc[q.Addr.Type]
+
+	clause := q.dialect().Lock(q.Lock, q.LockWait)
+	if clause == "" {
+		return errs.Newf(errs.ErrReqParamInvalid, "%s does not support lock [%s]", db, q.Lock)
+	}
+
+	// find 固定只取一条，find_all 指定了 size、page、from 或游标分页时都会生成分页语句
+	if !q.dialect().LockWithLimit() &&
+		(q.OP == consts.OpFind || q.Size > 0 || q.Page > 0 || q.From > 0 || q.cursorMode) {
+		return errs.Newf(errs.ErrReqParamInvalid,
+			"%s does not support lock [%s] with limit or offset, use find_all without size instead", db, q.Lock)
+	}
+
+	statement.Lock(clause)
+	return nil
+}
+
+// checkConflict replace、upsert 冲突检查，clickhouse 不支持 upsert，部分数据库必须指定冲突列
+func (q *Query) checkConflict(op string) error {
+	if q.SQL != "" {
+		return nil
+	}
+
+	db, _ := consts.DBTypeDesc[q.Addr.Type]
+
+	if op == "upsert" && q.Addr.Type == consts.DBTypeClickHouse {
+		return errs.Newf(errs.ErrReqParamInvalid, "%s does not support upsert", db)
+	}
+
+	if q.dialect().NeedConflict() && len(q.OnConflict) == 0 {
+		return errs.Newf(errs.ErrReqParamInvalid, "%s %s must specify the conflict columns by on_conflict", db, op)
+	}
+
+	return nil
+}
+
+// DryRun 试运行，返回将要执行的 sql 语句及参数，不访问数据库
+func (q *Query) DryRun(ctx context.Context) ([]*obj.Statement, error) {
+	q.dryRun = true
+
+	_, _, _, err := q.Query(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.statements, nil
+}
+
+// record 试运行记录语句
+func (q *Query) record(sql string, params []interface{}) {
+	q.statements = append(q.statements, &obj.Statement{Query: sql, Params: params})
+}
+
 // GetQuery 获取 sql 语句
 func (q *Query) GetQuery() string {
 	return q.SQL
@@ -0,0 +1,320 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/json"
+	"github.com/horm-database/common/types"
+)
+
+// StructTag 未指定 tag（Query.Tag 为空）时，结构体字段与列名映射默认使用的 tag
+var StructTag = "orm"
+
+var (
+	fieldsCache sync.Map // 结构体列名与字段的映射，key 为 fieldsKey，value 为 map[string][]int
+
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// structReceiver 获取可以直接扫描的结构体数组接收者，接收者需为 *[]Struct 或 *[]*Struct，
+// clickhouse（需要按列类型特殊转换）与分页查询（结果需要包含分页信息）不支持直接扫描。
+func (q *Query) structReceiver() (reflect.Value, bool) {
+	if q.Receiver == nil || q.Page > 0 || q.Addr.Type == consts.DBTypeClickHouse {
+		return reflect.Value{}, false
+	}
+
+	dest := reflect.ValueOf(q.Receiver)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() || dest.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, false
+	}
+
+	elemType := dest.Elem().Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct || elemType == timeType {
+		return reflect.Value{}, false
+	}
+
+	return dest, true
+}
+
+// FindAllStruct 查询符合要求的所有数据，结果直接扫描到结构体数组 dest（*[]Struct 或 *[]*Struct）中，不经过中间 map
+func (q *Query) FindAllStruct(ctx context.Context, dest reflect.Value) (int, error) {
+	slice := dest.Elem()
+	slice.SetLen(0)
+
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	var scanner *structScanner
+
+	next := func(rows *sql.Rows) (err error) {
+		if scanner == nil {
+			scanner, err = newStructScanner(rows, elemType, q.tag())
+			if err != nil {
+				return err
+			}
+		}
+
+		elem := reflect.New(elemType)
+
+		err = rows.Scan(scanner.targets(elem.Elem())...)
+		if err != nil {
+			return err
+		}
+
+		if isPtr {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+
+		return nil
+	}
+
+	err := q.query(ctx, next, q.SQL, q.Params...)
+
+	dest.Elem().Set(slice)
+	return slice.Len(), err
+}
+
+// structScanner 结构体扫描器，列与字段的对应关系在首行解析，后续行复用
+type structScanner struct {
+	fields   [][]int // 每列对应的字段 index，nil 表示结构体中没有该列
+	nullable []bool  // 每列是否可能为 NULL
+	discard  sql.RawBytes
+}
+
+func newStructScanner(rows *sql.Rows, typ reflect.Type, tag string) (*structScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	fieldMap := structFields(typ, tag)
+
+	s := structScanner{
+		fields:   make([][]int, len(columns)),
+		nullable: make([]bool, len(columns)),
+	}
+
+	for i, column := range columns {
+		index, ok := fieldMap[column]
+		if !ok {
+			index = fieldMap[strings.ToLower(column)]
+		}
+
+		s.fields[i] = index
+
+		nullable, ok := colTypes[i].Nullable()
+		s.nullable[i] = nullable || !ok
+	}
+
+	return &s, nil
+}
+
+// targets 获取一行数据的扫描目标，v 为结构体
+func (s *structScanner) targets(v reflect.Value) []interface{} {
+	targets := make([]interface{}, len(s.fields))
+
+	for i, index := range s.fields {
+		if index == nil {
+			targets[i] = &s.discard
+			continue
+		}
+
+		field := v.FieldByIndex(index)
+
+		if reflect.PtrTo(field.Type()).Implements(scannerType) || (!s.nullable[i] && directScan(field.Type())) {
+			targets[i] = field.Addr().Interface()
+		} else {
+			targets[i] = &fieldScanner{field: field}
+		}
+	}
+
+	return targets
+}
+
+// directScan 不为 NULL 时，database/sql 可以直接赋值的字段类型
+func directScan(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return typ.Elem().Kind() == reflect.Uint8
+	case reflect.Struct:
+		return typ == timeType
+	}
+	return false
+}
+
+// fieldScanner 结构体字段扫描，支持 NULL、指针字段，以及 json 列到结构体、map、数组字段的转换
+type fieldScanner struct {
+	field reflect.Value
+}
+
+// Scan 实现 sql.Scanner 接口
+func (f *fieldScanner) Scan(value interface{}) (err error) {
+	field := f.field
+
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		field.SetBool(types.ToBool(value))
+	case reflect.String:
+		field.SetString(types.ToString(value))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i64 int64
+		i64, err = types.ToInt64(value)
+		field.SetInt(i64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var ui64 uint64
+		ui64, err = types.ToUint64(value)
+		field.SetUint(ui64)
+	case reflect.Float32, reflect.Float64:
+		var f64 float64
+		f64, err = types.ToFloat64(value)
+		field.SetFloat(f64)
+	default:
+		if field.Type() == timeType {
+			var t time.Time
+			t, err = types.ParseTime(value, loc, "")
+			field.Set(reflect.ValueOf(t))
+		} else if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes(append([]byte{}, toBytes(value)...))
+		} else if field.Kind() == reflect.Interface {
+			field.Set(reflect.ValueOf(value))
+		} else { // json 列
+			err = json.Api.Unmarshal(toBytes(value), field.Addr().Interface())
+		}
+	}
+
+	return err
+}
+
+func toBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	default:
+		return []byte(types.ToString(value))
+	}
+}
+
+// tag 结构体字段与列名映射的 tag，与编解码器的 tag 保持一致
+func (q *Query) tag() string {
+	if q.Tag != "" {
+		return q.Tag
+	}
+	return StructTag
+}
+
+// fieldsKey 结构体字段映射缓存的 key，同一结构体不同 tag 的映射不同
+type fieldsKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// structFields 获取结构体列名与字段 index 的映射，列名取 tag 的值，未设置 tag 时取字段名，匿名结构体字段会展开
+func structFields(typ reflect.Type, tag string) map[string][]int {
+	key := fieldsKey{typ: typ, tag: tag}
+
+	if v, ok := fieldsCache.Load(key); ok {
+		return v.(map[string][]int)
+	}
+
+	fields := map[string][]int{}
+	parseStructFields(typ, tag, nil, fields)
+
+	v, _ := fieldsCache.LoadOrStore(key, fields)
+	return v.(map[string][]int)
+}
+
+func parseStructFields(typ reflect.Type, tagName string, parent []int, fields map[string][]int) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		tag := field.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.TrimSpace(strings.Split(tag, ",")[0])
+
+		index := make([]int, len(parent)+1)
+		copy(index, parent)
+		index[len(parent)] = i
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			parseStructFields(field.Type, tagName, index, fields)
+			continue
+		}
+
+		if field.PkgPath != "" { // 非导出字段
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if _, ok := fields[name]; !ok {
+			fields[name] = index
+		}
+
+		lower := strings.ToLower(name)
+		if _, ok := fields[lower]; !ok {
+			fields[lower] = index
+		}
+	}
+}
@@ -17,22 +17,20 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/horm-database/common/consts"
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/types"
 	"github.com/horm-database/orm/database/sql/client"
+	ol "github.com/horm-database/orm/log"
 )
 
 // Find 查询符合要求的一条数据，返回结果为 map[string]string
 func (q *Query) Find(ctx context.Context) (result map[string]interface{}, err error) {
 	next := func(rows *sql.Rows) error {
-		if q.Addr.Type == consts.DBTypeClickHouse {
-			result, err = q.nextRowCK(rows)
-		} else {
-			result, err = q.nextRow(rows)
-		}
+		result, err = q.dialect().NextRow(q, rows)
 
 		return err
 	}
@@ -46,11 +44,7 @@ func (q *Query) FindAll(ctx context.Context) (result []map[string]interface{}, e
 	next := func(rows *sql.Rows) (err error) {
 		var item map[string]interface{}
 
-		if q.Addr.Type == consts.DBTypeClickHouse {
-			item, err = q.nextRowCK(rows)
-		} else {
-			item, err = q.nextRow(rows)
-		}
+		item, err = q.dialect().NextRow(q, rows)
 
 		if err != nil {
 			return err
@@ -65,6 +59,61 @@ func (q *Query) FindAll(ctx context.Context) (result []map[string]interface{}, e
 	return
 }
 
+// Stream 流式查询符合要求的所有数据，每读取一行调用一次 next，不在内存中累积结果。next 返回 client.ErrBreak 时提前结束。
+func (q *Query) Stream(ctx context.Context, next func(row map[string]interface{}) error) error {
+	if q.OP != consts.OpFind && q.OP != consts.OpFindAll {
+		return errs.Newf(errs.ErrDBParams, "sql stream not support op %s", q.OP)
+	}
+
+	q.TimeLog = ol.NewTimeLog(ctx, q.Addr)
+
+	if q.SQL == "" {
+		statement, err := q.statement()
+		if err != nil {
+			return err
+		}
+
+		if q.OP == consts.OpFind {
+			q.Size = 1
+		} else if q.Page > 0 {
+			q.From = uint64((q.Page - 1) * q.Size)
+		}
+
+		statement.limit = q.Size
+		statement.offset = q.From
+
+		q.SQL = statement.GetSQL()
+		q.Params = statement.params
+	}
+
+	q.streaming = true
+
+	var nextErr error // next 返回的错误原样返回，不作为数据库错误记录
+
+	fn := func(rows *sql.Rows) (err error) {
+		var row map[string]interface{}
+
+		row, err = q.dialect().NextRow(q, rows)
+
+		if err != nil {
+			return err
+		}
+
+		if nextErr = next(row); nextErr != nil {
+			return client.ErrBreak
+		}
+
+		return nil
+	}
+
+	err := q.query(ctx, fn, q.SQL, q.Params...)
+	if err != nil {
+		return err
+	}
+
+	return nextErr
+}
+
 // Count 统计总数
 func (q *Query) Count(ctx context.Context) (uint64, error) {
 	var count uint64
@@ -77,9 +126,18 @@ func (q *Query) Count(ctx context.Context) (uint64, error) {
 	return count, err
 }
 
-// Transaction 事务函数
+// Transaction 事务函数，如果已处于外部事务中，则直接复用外部事务的 client，由外部事务统一提交或回滚。
 func (q *Query) Transaction(ctx context.Context, fn func(c *Query) error) error {
-	err := q.client.BeginTx(ctx)
+	err := q.initClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if q.TransInfo != nil {
+		return fn(q)
+	}
+
+	err = q.client.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -91,6 +149,11 @@ func (q *Query) Transaction(ctx context.Context, fn func(c *Query) error) error
 
 // execute 原生操作支持，支持自定义sql语句，比如delete，update,insert,replace
 func (q *Query) execute(ctx context.Context) (int64, int64, error) {
+	if q.dryRun {
+		q.record(q.SQL, q.Params)
+		return 0, 0, nil
+	}
+
 	err := q.initClient(ctx)
 	if err != nil {
 		return 0, 0, err
@@ -108,11 +171,14 @@ func (q *Query) execute(ctx context.Context) (int64, int64, error) {
 			"%s query get RowsAffected Error: [%v], sql=[%s], params=[%v]", db, err, q.SQL, q.Params)
 	}
 
-	lastInsertID, err := ret.LastInsertId()
-	if err != nil {
-		db, _ := consts.DBTypeDesc[q.Addr.Type]
-		q.TimeLog.Errorf(errs.ErrAffectResult,
-			"%s query get LastInsertId Error: [%v], sql=[%s], params=[%v]", db, err, q.SQL, q.Params)
+	var lastInsertID int64
+	if q.dialect().LastInsertID() { // 不支持 LastInsertId 的数据库（比如 postgresql）需通过 returning 获取
+		lastInsertID, err = ret.LastInsertId()
+		if err != nil {
+			db, _ := consts.DBTypeDesc[q.Addr.Type]
+			q.TimeLog.Errorf(errs.ErrAffectResult,
+				"%s query get LastInsertId Error: [%v], sql=[%s], params=[%v]", db, err, q.SQL, q.Params)
+		}
 	}
 
 	q.logInfo(q.SQL, q.Params)
@@ -121,12 +187,22 @@ func (q *Query) execute(ctx context.Context) (int64, int64, error) {
 }
 
 func (q *Query) query(ctx context.Context, next func(*sql.Rows) error, sql string, args ...interface{}) (err error) {
+	if q.dryRun {
+		q.record(sql, args)
+		return nil
+	}
+
 	err = q.initClient(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = q.client.Query(ctx, next, sql, args...)
+	if q.streaming {
+		err = q.client.Stream(ctx, next, sql, args...)
+	} else {
+		err = q.client.Query(ctx, next, sql, args...)
+	}
+
 	if err != nil {
 		return q.logError(err, sql, args)
 	}
@@ -145,12 +221,28 @@ func (q *Query) initClient(ctx context.Context) (err error) {
 				return
 			}
 
-			err = q.client.BeginTx(ctx)
+			if q.TransInfo.Ctx != nil { // 事务需要在整个生命周期内使用同一个上下文，否则上下文结束时事务会被自动回滚
+				ctx = q.TransInfo.Ctx
+			}
+
+			err = q.client.BeginTx(ctx, q.TransInfo.Options)
 			if err != nil {
 				return
 			}
 
+			// 嵌套事务中首次加入的 db，需要补齐外层已创建的保存点，才能回滚到对应的嵌套层级
+			for _, savepoint := range q.TransInfo.Savepoints {
+				err = q.client.Savepoint(ctx, savepoint)
+				if err != nil {
+					_ = q.client.FinishTx(err)
+					return
+				}
+			}
+
 			q.TransInfo.SetTxClient(q.Addr.Conn.DSN, q.client)
+			if q.DB != nil {
+				q.TransInfo.SetDBName(q.Addr.Conn.DSN, q.DB.Name)
+			}
 		} else {
 			q.client = txClient
 		}
@@ -158,7 +250,12 @@ func (q *Query) initClient(ctx context.Context) (err error) {
 		q.client, err = client.NewClient(q.Addr)
 	}
 
-	return nil
+	return err
+}
+
+// dialect 获取数据库方言
+func (q *Query) dialect() sqlDialect {
+	return getDialect(q.Addr.Type)
 }
 
 func (q *Query) nextRow(rows *sql.Rows) (map[string]interface{}, error) {
@@ -177,9 +274,18 @@ func (q *Query) nextRow(rows *sql.Rows) (map[string]interface{}, error) {
 	var row = make([]interface{}, len(columns))
 
 	for i := range columns {
-		nullable, _ := colTypes[i].Nullable()
+		nullable, ok := colTypes[i].Nullable()
+		if !ok { // 驱动不支持判断是否可空（比如 postgresql），按可空处理
+			nullable = true
+		}
+
 		typeName := colTypes[i].DatabaseTypeName()
-		typ := MySQLTypeMap[typeName]
+		typ, ok := q.dialect().TypeMap()[typeName]
+		if !ok { // 去掉长度、精度，比如 sqlite 的 VARCHAR(64)、DECIMAL(10,2)
+			if index := strings.IndexByte(typeName, '('); index != -1 {
+				typ = q.dialect().TypeMap()[strings.TrimSpace(typeName[:index])]
+			}
+		}
 
 		switch typ {
 		case types.TypeInt:
@@ -16,6 +16,7 @@ package sql
 
 import (
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/horm-database/common/consts"
@@ -42,7 +43,14 @@ type Statement struct {
 	params         []interface{}
 	forUpdate      string
 	indexHints     string
+	optimizerHints string // 优化器提示，输出为 SELECT /*+ ... */
 	conflictTarget string // PostgreSQL InsertOnDuplicateKeyUpdate: For ON CONFLICT DO UPDATE, a conflict_target must be provided.
+	returning      string // PostgreSQL RETURNING 返回列
+
+	columns         []string // insert、replace 的列
+	conflictColumns []string // 冲突判断列
+	upsert          bool     // 插入冲突时更新
+	updateColumns   []string // upsert 冲突时更新的列或表达式
 
 	condBuilder *strings.Builder
 	condParams  []interface{}
@@ -76,7 +84,7 @@ func (s *Statement) SetColumn(column []string) *Statement {
 				columnStr.WriteString(",")
 			}
 
-			columnStr.WriteString(columnQuote(v))
+			columnStr.WriteString(s.columnQuote(v))
 		}
 		s.selects = columnStr.String()
 	}
@@ -109,25 +117,27 @@ func (s *Statement) SetMaps(attributeArr []map[string]interface{}) *Statement {
 		return s
 	}
 
-	var i int
 	var setBuilder = strings.Builder{}
 	setBuilder.WriteString("(")
 
-	keys := []string{}
+	keys := make([]string, 0, len(attributeArr[0]))
 	for key := range attributeArr[0] {
 		keys = append(keys, key)
+	}
+
+	sort.Strings(keys) // 固定列的顺序，保证生成的语句稳定
 
+	for i, key := range keys {
 		if i == 0 {
-			setBuilder.WriteString(columnQuote(key))
+			setBuilder.WriteString(s.columnQuote(key))
 		} else {
 			setBuilder.WriteString(`,`)
-			setBuilder.WriteString(columnQuote(key))
+			setBuilder.WriteString(s.columnQuote(key))
 		}
-
-		i++
 	}
 
 	setBuilder.WriteString(") values ")
+	s.columns = keys
 
 	for k, attributes := range attributeArr {
 		if k == 0 {
@@ -164,11 +174,11 @@ func (s *Statement) UpdateMap(attributes map[string]interface{}) *Statement {
 	var i int
 	for key, value := range attributes {
 		if i == 0 {
-			setBuilder.WriteString(columnQuote(key))
+			setBuilder.WriteString(s.columnQuote(key))
 			setBuilder.WriteString("=?")
 		} else {
 			setBuilder.WriteString(",")
-			setBuilder.WriteString(columnQuote(key))
+			setBuilder.WriteString(s.columnQuote(key))
 			setBuilder.WriteString("=?")
 		}
 
@@ -230,7 +240,7 @@ func (s *Statement) Group(group []string) *Statement {
 	if l == 1 {
 		s.group = group[0]
 	} else if l > 1 {
-		s.group = "`" + strings.Join(group, "`,`") + "`"
+		s.group = s.quoteList(group)
 	}
 
 	return s
@@ -277,20 +287,20 @@ func (s *Statement) realJoin(table string, joinType string, using []string, on m
 
 	var joinBuilder = strings.Builder{}
 	joinBuilder.WriteString(joinType)
-	joinBuilder.WriteString(" JOIN `")
-	joinBuilder.WriteString(table)
-	joinBuilder.WriteString("` ")
+	joinBuilder.WriteString(" JOIN ")
 
 	if joinAlias != "" {
-		joinBuilder.WriteString("AS `")
-		joinBuilder.WriteString(joinAlias)
-		joinBuilder.WriteString("` ")
+		joinBuilder.WriteString(s.dialect().Table(table, joinAlias))
+	} else {
+		joinBuilder.WriteString(s.quote(table))
 	}
 
+	joinBuilder.WriteString(" ")
+
 	if len(using) > 0 {
-		joinBuilder.WriteString("USING (`")
-		joinBuilder.WriteString(strings.Join(using, "`,`"))
-		joinBuilder.WriteString("`) ")
+		joinBuilder.WriteString("USING (")
+		joinBuilder.WriteString(s.quoteList(using))
+		joinBuilder.WriteString(") ")
 	} else if len(on) > 0 {
 		joinBuilder.WriteString("ON ")
 
@@ -302,41 +312,30 @@ func (s *Statement) realJoin(table string, joinType string, using []string, on m
 
 			dotIndex := strings.Index(key, ".")
 			if dotIndex != -1 {
-				tableColumn = columnQuote(key)
+				tableColumn = s.columnQuote(key)
 			} else {
 				if s.alias != "" {
-					tableColumn = "`" + s.alias + "`.`" + key + "`"
+					tableColumn = s.quote(s.alias) + "." + s.quote(key)
 				} else {
-					tableColumn = "`" + s.table + "`.`" + key + "`"
+					tableColumn = s.quote(s.table) + "." + s.quote(key)
 				}
 			}
 
 			i++
 
+			joinTable := table
 			if joinAlias != "" {
-				joinBuilder.WriteString(tableColumn)
-				joinBuilder.WriteString("=`")
-				joinBuilder.WriteString(joinAlias)
-				joinBuilder.WriteString("`.`")
-				joinBuilder.WriteString(value)
-
-				if i == l {
-					joinBuilder.WriteString("`")
-				} else {
-					joinBuilder.WriteString("` AND")
-				}
+				joinTable = joinAlias
+			}
 
-			} else {
-				joinBuilder.WriteString(tableColumn)
-				joinBuilder.WriteString("=`")
-				joinBuilder.WriteString(table)
-				joinBuilder.WriteString("`.`")
-				joinBuilder.WriteString(value)
-				if i == l {
-					joinBuilder.WriteString("`")
-				} else {
-					joinBuilder.WriteString("` AND")
-				}
+			joinBuilder.WriteString(tableColumn)
+			joinBuilder.WriteString("=")
+			joinBuilder.WriteString(s.quote(joinTable))
+			joinBuilder.WriteString(".")
+			joinBuilder.WriteString(s.quote(value))
+
+			if i != l {
+				joinBuilder.WriteString(" AND")
 			}
 		}
 	}
@@ -413,7 +412,7 @@ func (s *Statement) whereImplode(dbType int, index *int, i int, key string, valu
 			s.condParams = append(s.condParams, value)
 		}
 	} else if column != "" {
-		column = columnQuote(column)
+		column = s.columnQuote(column)
 		switch operator {
 		case consts.OPGt, consts.OPGte, consts.OPLt, consts.OPLte:
 			s.condBuilder.WriteString(connector)
@@ -540,22 +539,113 @@ func (s *Statement) handleLikeArray(value reflect.Value, column, operator string
 }
 
 // 列处理
-func columnQuote(str string) (tableColumn string) {
+func columnQuote(dbType int, str string) (tableColumn string) {
 	str = strings.TrimSpace(str)
 	isSpecial, hasDot, dotIndex := hasSpecial(str)
 	if isSpecial {
 		return " " + str + " "
 	}
 
+	dialect := getDialect(dbType)
+
 	if hasDot {
-		tableColumn = " `" + strings.TrimSpace(str[:dotIndex]) + "`.`" + strings.TrimSpace(str[dotIndex+1:]) + "` "
+		tableColumn = " " + dialect.Quote(strings.TrimSpace(str[:dotIndex])) +
+			"." + dialect.Quote(strings.TrimSpace(str[dotIndex+1:])) + " "
 	} else {
-		tableColumn = " `" + strings.TrimSpace(str) + "` "
+		tableColumn = " " + dialect.Quote(str) + " "
 	}
 
 	return
 }
 
+// dialect 获取数据库方言
+func (s *Statement) dialect() sqlDialect {
+	return getDialect(s.dbType)
+}
+
+// columnQuote 列处理
+func (s *Statement) columnQuote(str string) string {
+	return columnQuote(s.dbType, str)
+}
+
+// quote 表名、别名等标识符加引号
+func (s *Statement) quote(name string) string {
+	return s.dialect().Quote(name)
+}
+
+// quoteList 多个标识符加引号，逗号分隔
+func (s *Statement) quoteList(names []string) string {
+	ret := strings.Builder{}
+	for k, name := range names {
+		if k > 0 {
+			ret.WriteString(",")
+		}
+		ret.WriteString(s.quote(strings.TrimSpace(name)))
+	}
+	return ret.String()
+}
+
+// OnConflict 设置冲突判断列，postgresql replace 时生成 ON CONFLICT (...) DO UPDATE 语句
+func (s *Statement) OnConflict(columns []string) *Statement {
+	if len(columns) > 0 {
+		s.conflictColumns = columns
+		s.conflictTarget = s.quoteList(columns)
+	}
+	return s
+}
+
+// Distinct 查询去重 SELECT DISTINCT，count 时统计去重后的条数
+func (s *Statement) Distinct(distinct bool) *Statement {
+	s.distinct = distinct
+	return s
+}
+
+// IndexHint 索引提示，clause 为方言生成的索引提示语句，多个提示依次追加
+func (s *Statement) IndexHint(clause string) *Statement {
+	if s.indexHints == "" {
+		s.indexHints = clause
+	} else {
+		s.indexHints += " " + clause
+	}
+	return s
+}
+
+// OptimizerHint 优化器提示，例如 MAX_EXECUTION_TIME(1000)、BKA(t1)
+func (s *Statement) OptimizerHint(hints []string) *Statement {
+	if len(hints) > 0 {
+		s.optimizerHints = strings.Join(hints, " ")
+	}
+	return s
+}
+
+// Lock 查询加锁，clause 为方言生成的行锁语句
+func (s *Statement) Lock(clause string) *Statement {
+	s.forUpdate = clause
+	return s
+}
+
+// Upsert 插入冲突时更新，columns 为冲突时更新的列，为空时更新所有插入的列（冲突列除外），包含 = 的为表达式
+func (s *Statement) Upsert(columns []string) *Statement {
+	s.upsert = true
+	s.updateColumns = columns
+	return s
+}
+
+// Returning 设置 insert、replace、update、delete 语句返回的列，方言需支持 RETURNING
+func (s *Statement) Returning(columns []string) *Statement {
+	if len(columns) > 0 {
+		ret := strings.Builder{}
+		for k, column := range columns {
+			if k > 0 {
+				ret.WriteString(",")
+			}
+			ret.WriteString(s.columnQuote(column))
+		}
+		s.returning = ret.String()
+	}
+	return s
+}
+
 func hasSpecial(str string) (isSpecial bool, hasDot bool, index int) {
 	index = -1
 
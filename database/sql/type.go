@@ -48,6 +48,78 @@ var MySQLTypeMap = map[string]types.Type{
 	"JSON":               types.TypeJSON,
 }
 
+var PostgreSQLTypeMap = map[string]types.Type{
+	"INT2":        types.TypeInt16,
+	"INT4":        types.TypeInt32,
+	"INT8":        types.TypeInt64,
+	"SERIAL":      types.TypeInt32,
+	"BIGSERIAL":   types.TypeInt64,
+	"FLOAT4":      types.TypeFloat,
+	"FLOAT8":      types.TypeDouble,
+	"NUMERIC":     types.TypeDouble,
+	"VARCHAR":     types.TypeString,
+	"BPCHAR":      types.TypeString,
+	"TEXT":        types.TypeString,
+	"UUID":        types.TypeString,
+	"TIME":        types.TypeString,
+	"TIMETZ":      types.TypeString,
+	"BYTEA":       types.TypeBytes,
+	"DATE":        types.TypeTime,
+	"TIMESTAMP":   types.TypeTime,
+	"TIMESTAMPTZ": types.TypeTime,
+	"JSON":        types.TypeJSON,
+	"JSONB":       types.TypeJSON,
+}
+
+// SQLiteTypeMap sqlite 按建表时声明的类型映射，类型亲和性为 NUMERIC 的列（比如 BOOLEAN）不做转换
+var SQLiteTypeMap = map[string]types.Type{
+	"INT":       types.TypeInt64,
+	"INTEGER":   types.TypeInt64,
+	"TINYINT":   types.TypeInt8,
+	"SMALLINT":  types.TypeInt16,
+	"MEDIUMINT": types.TypeInt32,
+	"BIGINT":    types.TypeInt64,
+	"REAL":      types.TypeDouble,
+	"FLOAT":     types.TypeDouble,
+	"DOUBLE":    types.TypeDouble,
+	"NUMERIC":   types.TypeDouble,
+	"DECIMAL":   types.TypeDouble,
+	"TEXT":      types.TypeString,
+	"VARCHAR":   types.TypeString,
+	"CHAR":      types.TypeString,
+	"CLOB":      types.TypeString,
+	"BLOB":      types.TypeBytes,
+	"DATE":      types.TypeTime,
+	"DATETIME":  types.TypeTime,
+	"TIMESTAMP": types.TypeTime,
+	"JSON":      types.TypeJSON,
+}
+
+// OracleTypeMap oracle 类型映射，NUMBER 既可能是整数也可能是小数，由驱动决定返回类型
+var OracleTypeMap = map[string]types.Type{
+	"BINARY_FLOAT":                   types.TypeFloat,
+	"BINARY_DOUBLE":                  types.TypeDouble,
+	"FLOAT":                          types.TypeDouble,
+	"VARCHAR":                        types.TypeString,
+	"VARCHAR2":                       types.TypeString,
+	"NVARCHAR2":                      types.TypeString,
+	"CHAR":                           types.TypeString,
+	"NCHAR":                          types.TypeString,
+	"LONG":                           types.TypeString,
+	"CLOB":                           types.TypeString,
+	"NCLOB":                          types.TypeString,
+	"ROWID":                          types.TypeString,
+	"UROWID":                         types.TypeString,
+	"RAW":                            types.TypeBytes,
+	"LONG RAW":                       types.TypeBytes,
+	"BLOB":                           types.TypeBytes,
+	"DATE":                           types.TypeTime,
+	"TIMESTAMP":                      types.TypeTime,
+	"TIMESTAMP WITH TIME ZONE":       types.TypeTime,
+	"TIMESTAMP WITH LOCAL TIME ZONE": types.TypeTime,
+	"JSON":                           types.TypeJSON,
+}
+
 var ClickHouseTypeMap = map[string]types.Type{
 	"Int":         types.TypeInt,
 	"Int8":        types.TypeInt8,
@@ -17,6 +17,7 @@ package sql
 import (
 	j "encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -48,60 +49,104 @@ func (q *Query) logError(err error, sql string, params []interface{}) error {
 	db, _ := consts.DBTypeDesc[q.Addr.Type]
 	if !log.OmitError(q.Addr) {
 		q.TimeLog.Errorf(errs.Code(err), "%s query error: [%v], sql=[%s]",
-			db, errs.Msg(err), GetSQLWithParams(sql, params))
+			db, errs.Msg(err), sqlWithParams(q.dialect(), sql, params))
 	}
 
 	return errs.NewDBf(errs.Code(err), "%s query error: [%v]", db, errs.Msg(err))
 }
 
+// GetSQLWithParams 将参数代入 ? 占位符的 sql 语句，引号内的 ? 原样输出
 func GetSQLWithParams(sql string, params []interface{}) string {
+	return sqlWithParams(getDialect(consts.DBTypeMySQL), sql, params)
+}
+
+// sqlWithParams 按方言的占位符（?、postgresql 的 $n、oracle 的 :n）将参数代入 sql 语句，
+// 引号内的字符串、标识符原样输出，比如 '12:01:00' 不会被当成占位符
+func sqlWithParams(dialect sqlDialect, sql string, params []interface{}) string {
+	var prefix byte = '?'
+	numbered := dialect.Placeholder(1) != "?"
+	if numbered {
+		prefix = dialect.Placeholder(1)[0]
+	}
+
 	var last, paramsIndex int
+	var quote byte
 	result := strings.Builder{}
 
-	for k, c := range sql {
-		if c == '?' {
-			result.WriteString(sql[last:k])
+	for k := 0; k < len(sql); k++ {
+		c := sql[k]
 
-			if paramsIndex < len(params) {
-				if params[paramsIndex] == nil {
-					result.WriteString("null")
-				} else {
-					val := types.Indirect(params[paramsIndex])
-					switch v := val.(type) {
-					case string:
-						result.WriteString("'")
-						result.WriteString(v)
-						result.WriteString("'")
-					case []byte:
-						result.WriteString("'")
-						result.Write(v)
-						result.WriteString("'")
-					case bool:
-						result.WriteString(fmt.Sprintf("%v", v))
-					case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-						result.WriteString(fmt.Sprintf("%d", v))
-					case float32, float64:
-						result.WriteString(fmt.Sprintf("%f", v))
-					case j.Number:
-						result.WriteString("'")
-						result.WriteString(v.String())
-						result.WriteString("'")
-					case time.Time:
-						result.WriteString("'")
-						result.WriteString(v.Format(time.RFC3339Nano))
-						result.WriteString("'")
-					default:
-						result.WriteString(json.MarshalToString(v))
-					}
-				}
-			} else {
-				result.WriteString("?")
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
 			}
-
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == prefix && !numbered:
+			result.WriteString(sql[last:k])
+			writeParam(&result, params, paramsIndex, "?")
 			paramsIndex++
 			last = k + 1
+		case c == prefix:
+			end := k + 1
+			for end < len(sql) && sql[end] >= '0' && sql[end] <= '9' {
+				end++
+			}
+
+			if end == k+1 {
+				continue
+			}
+
+			n, _ := strconv.Atoi(sql[k+1 : end])
+			result.WriteString(sql[last:k])
+			writeParam(&result, params, n-1, sql[k:end])
+			last = end
+			k = end - 1
 		}
 	}
 
+	result.WriteString(sql[last:])
+
 	return result.String()
 }
+
+func writeParam(result *strings.Builder, params []interface{}, index int, placeholder string) {
+	if index < 0 || index >= len(params) {
+		result.WriteString(placeholder)
+		return
+	}
+
+	if params[index] == nil {
+		result.WriteString("null")
+		return
+	}
+
+	val := types.Indirect(params[index])
+	switch v := val.(type) {
+	case string:
+		result.WriteString("'")
+		result.WriteString(v)
+		result.WriteString("'")
+	case []byte:
+		result.WriteString("'")
+		result.Write(v)
+		result.WriteString("'")
+	case bool:
+		result.WriteString(fmt.Sprintf("%v", v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		result.WriteString(fmt.Sprintf("%d", v))
+	case float32, float64:
+		result.WriteString(fmt.Sprintf("%f", v))
+	case j.Number:
+		result.WriteString("'")
+		result.WriteString(v.String())
+		result.WriteString("'")
+	case time.Time:
+		result.WriteString("'")
+		result.WriteString(v.Format(time.RFC3339Nano))
+		result.WriteString("'")
+	default:
+		result.WriteString(json.MarshalToString(v))
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"testing"
+
+	"github.com/horm-database/common/consts"
+)
+
+func TestSQLWithParams(t *testing.T) {
+	params := []interface{}{1, "a"}
+
+	cases := []struct {
+		dbType int
+		sql    string
+		want   string
+	}{
+		{
+			dbType: consts.DBTypeMySQL,
+			sql:    "SELECT * FROM `t` WHERE `a` = ? AND `b` = ? AND `c` > '12:01:00' AND `d` != '$1?'",
+			want:   "SELECT * FROM `t` WHERE `a` = 1 AND `b` = 'a' AND `c` > '12:01:00' AND `d` != '$1?'",
+		},
+		{
+			dbType: consts.DBTypePostgreSQL,
+			sql:    `SELECT * FROM "t" WHERE "a" = $1 AND "b" = $2 AND "c" > '12:01:00' AND "d"::text != '?'`,
+			want:   `SELECT * FROM "t" WHERE "a" = 1 AND "b" = 'a' AND "c" > '12:01:00' AND "d"::text != '?'`,
+		},
+		{
+			dbType: consts.DBTypeOracle,
+			sql:    "SELECT * FROM t WHERE a = :1 AND b = :2 AND c > '12:01:00' AND d != '$1'",
+			want:   "SELECT * FROM t WHERE a = 1 AND b = 'a' AND c > '12:01:00' AND d != '$1'",
+		},
+	}
+
+	for _, c := range cases {
+		if got := sqlWithParams(getDialect(c.dbType), c.sql, params); got != c.want {
+			t.Errorf("%s sqlWithParams\n got: %s\nwant: %s", consts.DBTypeDesc[c.dbType], got, c.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+
+	"github.com/horm-database/common/proto"
+)
+
+// PageResult 分页查询结果
+type PageResult[T any] struct {
+	Detail *proto.Detail `orm:"detail,omitempty" json:"detail,omitempty"` // 查询细节信息
+	Data   []T           `orm:"data,omitempty" json:"data,omitempty"`     // 分页结果
+}
+
+// Find 执行查询并返回单条结果，o 需通过 Find 构建语句，例如：
+//
+//	user, isNil, err := orm.Find[User](ctx, db.Session().Name("user").Find(horm.Where{"id": 1}))
+func Find[T any](ctx context.Context, o *ORM) (T, bool, error) {
+	var ret T
+	isNil, err := o.Exec(ctx, &ret)
+	return ret, isNil, err
+}
+
+// FindAll 执行查询并返回所有结果，o 需通过 FindAll 构建语句，例如：
+//
+//	users, err := orm.FindAll[User](ctx, db.Session().Name("user").FindAll(horm.Where{"age >": 18}))
+func FindAll[T any](ctx context.Context, o *ORM) ([]T, error) {
+	var ret []T
+	_, err := o.Exec(ctx, &ret)
+	return ret, err
+}
+
+// Page 执行分页查询并返回分页结果，o 需通过 FindAll 与 Page 构建语句，例如：
+//
+//	page, err := orm.Page[User](ctx, db.Session().Name("user").FindAll(horm.Where{"age >": 18}).Page(1, 20))
+//
+// 结果不包含查询细节（比如未设置 Page）时，Detail 为 nil。
+func Page[T any](ctx context.Context, o *ORM) (*PageResult[T], error) {
+	page := PageResult[T]{}
+
+	_, err := o.exec(ctx, nil, func(ret interface{}) error {
+		if pageResult, ok := ret.(proto.PageResult); ok {
+			page.Detail = pageResult.Detail
+			ret = pageResult.Data
+		}
+
+		return o.decode(ret, &page.Data)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/types"
+	"github.com/horm-database/go-horm/horm/codec"
+)
+
+// iterBuffer 迭代器预读的行数
+const iterBuffer = 64
+
+// Iter 流式查询迭代器，后台逐行读取数据，内存占用与结果总数无关。sql 直接流式读取结果集，elastic 自动通过 scroll 翻页。
+// 使用完毕（包括提前退出）必须调用 Close 释放连接。
+type Iter struct {
+	rows   chan map[string]interface{}
+	row    map[string]interface{}
+	err    error
+	closed bool
+	cancel context.CancelFunc
+
+	coder     codec.Codec
+	requestID uint64
+}
+
+// Iter 流式执行 FindAll 查询，返回迭代器，例如：
+//
+//	iter := db.Session().Name("user").FindAll(horm.Where{"status": 1}).Iter(ctx)
+//	defer iter.Close()
+//
+//	for iter.Next() {
+//		var user User
+//		if err := iter.Scan(&user); err != nil {
+//			return err
+//		}
+//	}
+//
+//	return iter.Err()
+//
+// 注意：事务中迭代期间，事务连接被占用，迭代结束前不能在同一事务中执行其他语句。
+func (o *ORM) Iter(ctx context.Context) *Iter {
+	it := Iter{
+		rows:      make(chan map[string]interface{}, iterBuffer),
+		coder:     o.query.GetCoder(),
+		requestID: o.query.RequestID,
+	}
+
+	defer o.reset()
+
+	if err := o.checkExec(); err != nil {
+		it.err = err
+		close(it.rows)
+		return &it
+	}
+
+	tree, err := o.newTree()
+	if err != nil {
+		it.err = err
+		close(it.rows)
+		return &it
+	}
+
+	parent := ctx
+	ctx, it.cancel = context.WithCancel(parent)
+
+	go func() {
+		defer close(it.rows)
+
+		err := stream(ctx, tree, func(row map[string]interface{}) error {
+			select {
+			case it.rows <- row:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		if err != nil && ctx.Err() != nil && parent.Err() == nil { // 由 Close 主动取消，不是错误
+			err = nil
+		}
+
+		it.err = err
+	}()
+
+	return &it
+}
+
+// Next 读取下一行数据，没有更多数据或发生错误时返回 false，此时可以通过 Err 获取错误
+func (it *Iter) Next() bool {
+	if it.closed {
+		return false
+	}
+
+	row, ok := <-it.rows
+	if !ok {
+		it.row = nil
+		if it.cancel != nil {
+			it.cancel()
+		}
+		return false
+	}
+
+	it.row = row
+	return true
+}
+
+// Row 获取当前行的原始数据
+func (it *Iter) Row() map[string]interface{} {
+	return it.row
+}
+
+// Scan 将当前行数据解码到 dest
+func (it *Iter) Scan(dest interface{}) error {
+	if it.row == nil {
+		return errs.New(errs.ErrReqParamInvalid, "iter scan called without a successful next")
+	}
+
+	err := it.coder.Decode(0, it.row, []interface{}{dest})
+	if err != nil {
+		return errs.Newf(errs.ErrClientDecode,
+			"[request_id=%d] %v, result=[%s]", it.requestID, err, types.ToString(it.row))
+	}
+
+	return nil
+}
+
+// Err 返回迭代过程中的错误，需在 Next 返回 false 或 Close 之后调用
+func (it *Iter) Err() error {
+	return it.err
+}
+
+// Close 结束迭代，取消后台查询并释放连接，可以重复调用
+func (it *Iter) Close() error {
+	if it.closed {
+		return nil
+	}
+
+	it.closed = true
+	it.row = nil
+
+	if it.cancel != nil {
+		it.cancel()
+	}
+
+	for range it.rows { // 等待后台查询退出
+	}
+
+	return nil
+}
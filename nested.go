@@ -0,0 +1,314 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/horm-database/common/consts"
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/types"
+	"github.com/horm-database/common/util"
+	"github.com/horm-database/orm/obj"
+	"github.com/samber/lo"
+)
+
+// ParentPrefix 子查询 where 条件的值以该前缀开头时，表示引用父查询结果的字段，比如 Eq("user_id", "@parent.uid")
+const ParentPrefix = "@parent."
+
+// parentRef 子查询对父查询字段的引用
+type parentRef struct {
+	key    string // 子查询 where 条件 key
+	column string // 子查询关联列
+	field  string // 父查询结果字段
+}
+
+// AddSub 添加嵌套子查询，子查询的 where 条件可以通过 @parent.field 引用父查询结果的字段，例如：
+//
+//	orders := orm.NewORM("db").Name("order").FindAll(horm.Where{"status": 1})
+//	orders.AddSub(orm.NewORM("db").Name("user(buyer)").Find(horm.Where{"id": "@parent.uid"}))
+//
+// 所有父查询结果的字段值会合并为一次 IN 查询，子查询结果按关联字段挂载到每条父查询结果下，key 为子查询别名（未设置别名时为执行单元名），
+// 子查询为 Find 时挂载单条记录，FindAll 时挂载记录数组。注意：子查询的 limit 作用于合并后的整个查询。
+func (o *ORM) AddSub(subs ...*ORM) *ORM {
+	o.subs = append(o.subs, subs...)
+	return o
+}
+
+// initSubTree 初始化嵌套子查询节点
+func (o *ORM) initSubTree(node *obj.Tree) error {
+	var last *obj.Tree
+
+	for _, sub := range o.subs {
+		if sub.initErr != nil {
+			return sub.initErr
+		}
+
+		subNode, err := sub.newTree()
+		if err != nil {
+			return err
+		}
+
+		subNode.IsSub = true
+		subNode.Parent = node
+		subNode.TransInfo = node.TransInfo
+		subNode.InTrans = node.InTrans
+
+		if last == nil {
+			node.Sub = subNode
+		} else {
+			last.Next = subNode
+			subNode.Last = last
+		}
+
+		last = subNode
+	}
+
+	node.HasSub = node.Sub != nil
+	return nil
+}
+
+// execNode 执行查询节点及其嵌套子查询
+func execNode(ctx context.Context, node *obj.Tree) {
+	node.Result, node.Detail, node.IsNil, node.Error = query(ctx, node)
+
+	if node.Error == nil && node.HasSub && !node.IsNil {
+		node.Error = querySub(ctx, node)
+	}
+}
+
+// querySub 执行 node 的所有子查询，并将子查询结果挂载到 node 的每条结果记录下
+func querySub(ctx context.Context, node *obj.Tree) error {
+	rows := toRows(node.Result)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	for sub := node.Sub; sub != nil; sub = sub.Next {
+		sub.ParentRet = rows
+		node.SubQuery = append(node.SubQuery, sub)
+
+		err := querySubNode(ctx, sub, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// querySubNode 将父查询结果的引用字段合并为一次查询，查询结果按关联字段分组挂载到父查询结果下
+func querySubNode(ctx context.Context, sub *obj.Tree, parentRows []map[string]interface{}) error {
+	unit := sub.GetUnit()
+	refs := getParentRefs(unit.Where)
+	isArray := sub.IsArray()
+	key := subKey(sub)
+
+	if len(refs) > 0 {
+		if !setSubWhere(unit, refs, parentRows) { // 父查询结果中没有可关联的值
+			for _, row := range parentRows {
+				row[key] = emptySubResult(isArray)
+			}
+			return nil
+		}
+
+		for _, ref := range refs {
+			if len(unit.Column) > 0 && lo.IndexOf(unit.Column, ref.column) == -1 {
+				unit.Column = append(unit.Column, ref.column)
+			}
+		}
+
+		// 合并查询需要返回所有匹配记录
+		if !isArray {
+			sub.Property.Op = consts.OpFindAll
+			unit.Op = consts.OpFindAll
+			unit.Size = 0
+		}
+	}
+
+	execNode(ctx, sub)
+	if sub.Error != nil {
+		return errs.Newf(errs.Code(sub.Error), "sub query [%s] error: %s", sub.GetPath(), errs.Msg(sub.Error))
+	}
+
+	subRows := toRows(sub.Result)
+
+	if len(refs) == 0 { // 子查询与父查询没有关联，所有父查询结果挂载同样的子查询结果
+		for _, row := range parentRows {
+			if isArray {
+				row[key] = subRows
+			} else if len(subRows) > 0 {
+				row[key] = subRows[0]
+			} else {
+				row[key] = nil
+			}
+		}
+		return nil
+	}
+
+	groups := map[string][]map[string]interface{}{}
+	for _, subRow := range subRows {
+		groupKey := refKey(refs, subRow, true)
+		groups[groupKey] = append(groups[groupKey], subRow)
+	}
+
+	for _, row := range parentRows {
+		group := groups[refKey(refs, row, false)]
+		if isArray {
+			if group == nil {
+				group = []map[string]interface{}{}
+			}
+			row[key] = group
+		} else if len(group) > 0 {
+			row[key] = group[0]
+		} else {
+			row[key] = nil
+		}
+	}
+
+	return nil
+}
+
+// getParentRefs 获取 where 条件中对父查询字段的引用，仅支持顶层的等值条件
+func getParentRefs(where map[string]interface{}) []*parentRef {
+	var refs []*parentRef
+
+	for key, value := range where {
+		str, ok := value.(string)
+		if !ok || !strings.HasPrefix(str, ParentPrefix) {
+			continue
+		}
+
+		column, operator, _, _, _, _, _ := util.OperatorMatch(key, false)
+		if operator != "" && operator != consts.OPEqual {
+			continue
+		}
+
+		refs = append(refs, &parentRef{key: key, column: column, field: str[len(ParentPrefix):]})
+	}
+
+	return refs
+}
+
+// setSubWhere 将父查询结果中引用字段的值去重后替换到子查询 where 条件，单个引用字段使用 IN 查询，
+// 多个引用字段使用 (a=? AND b=?) OR (a=? AND b=?) 查询。返回 false 表示没有可关联的值。
+func setSubWhere(unit *proto.Unit, refs []*parentRef, parentRows []map[string]interface{}) bool {
+	for _, ref := range refs {
+		delete(unit.Where, ref.key)
+	}
+
+	exists := map[string]bool{}
+
+	if len(refs) == 1 {
+		var values []interface{}
+		for _, row := range parentRows {
+			value := row[refs[0].field]
+			if value == nil {
+				continue
+			}
+
+			k := types.ToString(value)
+			if !exists[k] {
+				exists[k] = true
+				values = append(values, value)
+			}
+		}
+
+		if len(values) == 0 {
+			return false
+		}
+
+		unit.Where[refs[0].column] = values
+		return true
+	}
+
+	var or []map[string]interface{}
+	for _, row := range parentRows {
+		k := refKey(refs, row, false)
+		if exists[k] {
+			continue
+		}
+		exists[k] = true
+
+		cond := map[string]interface{}{}
+		for _, ref := range refs {
+			cond[ref.column] = row[ref.field]
+		}
+		or = append(or, cond)
+	}
+
+	if len(or) == 0 {
+		return false
+	}
+
+	unit.Where[consts.OR] = or
+	return true
+}
+
+// refKey 关联字段值组合成的分组 key，isSub 为 true 时取子查询关联列的值，否则取父查询引用字段的值
+func refKey(refs []*parentRef, row map[string]interface{}, isSub bool) string {
+	key := strings.Builder{}
+
+	for k, ref := range refs {
+		if k > 0 {
+			key.WriteString("\x00")
+		}
+
+		if isSub {
+			key.WriteString(types.ToString(row[ref.column]))
+		} else {
+			key.WriteString(types.ToString(row[ref.field]))
+		}
+	}
+
+	return key.String()
+}
+
+// subKey 子查询结果在父查询结果中的 key，优先取别名
+func subKey(sub *obj.Tree) string {
+	if sub.GetAlias() != "" {
+		return sub.GetAlias()
+	}
+	return sub.GetName()
+}
+
+func emptySubResult(isArray bool) interface{} {
+	if isArray {
+		return []map[string]interface{}{}
+	}
+	return nil
+}
+
+// toRows 将查询结果转化为记录数组
+func toRows(result interface{}) []map[string]interface{} {
+	switch v := result.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if row, ok := item.(map[string]interface{}); ok {
+				rows = append(rows, row)
+			}
+		}
+		return rows
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
+	"github.com/horm-database/common/util"
+)
+
+// DefaultCursorOrder 游标分页未设置排序时的默认排序列
+const DefaultCursorOrder = "id"
+
+// cursorTimeLayout 游标中时间的格式
+const cursorTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// EncodeCursor 将排序列的值编码为不透明的游标
+func EncodeCursor(values []interface{}) (string, error) {
+	items := make([]interface{}, len(values))
+	for k, v := range values {
+		if t, ok := v.(time.Time); ok {
+			items[k] = t.Format(cursorTimeLayout)
+		} else {
+			items[k] = v
+		}
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return "", errs.Newf(errs.ErrReqParamInvalid, "encode cursor error: %v", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor 解码游标，数字解码为 json.Number，避免大整数丢失精度
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errs.Newf(errs.ErrReqParamInvalid, "cursor [%s] invalid: %v", cursor, err)
+	}
+
+	var values []interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(&values); err != nil {
+		return nil, errs.Newf(errs.ErrReqParamInvalid, "cursor [%s] invalid: %v", cursor, err)
+	}
+
+	return values, nil
+}
+
+// CursorOrders 游标分页的排序列，未设置排序时为 id 升序
+func CursorOrders(orders []string) []*util.Order {
+	ret := util.FormatOrders(orders)
+	if len(ret) == 0 {
+		ret = []*util.Order{{Field: DefaultCursorOrder, Ascending: true}}
+	}
+	return ret
+}
+
+// CursorValues 解码游标并校验与排序列一一对应，cursor 为空表示首页，返回 nil
+func CursorValues(cursor string, orders []*util.Order) ([]interface{}, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	values, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != len(orders) {
+		return nil, errs.Newf(errs.ErrReqParamInvalid,
+			"cursor [%s] does not match order columns %d", cursor, len(orders))
+	}
+
+	return values, nil
+}
+
+// CursorResult 处理游标分页结果，rows 为多查询一条的结果，用于判断是否还有下一页，
+// 返回截断后的结果以及包含 has_more、cursor（下一页游标，取最后一条记录排序列的值）的查询细节。
+func CursorResult(rows []map[string]interface{},
+	size int, orders []*util.Order) ([]map[string]interface{}, *proto.Detail, error) {
+	detail := proto.Detail{Size: size, Extras: map[string]interface{}{"has_more": false, "cursor": ""}}
+
+	if len(rows) <= size {
+		return rows, &detail, nil
+	}
+
+	rows = rows[:size]
+	last := rows[size-1]
+
+	values := make([]interface{}, len(orders))
+	for k, order := range orders {
+		v, ok := last[order.Field]
+		if !ok { // 带表别名的排序列，比如 u.id
+			if index := strings.LastIndex(order.Field, "."); index != -1 {
+				v, ok = last[order.Field[index+1:]]
+			}
+		}
+
+		if !ok {
+			return nil, nil, errs.Newf(errs.ErrReqParamInvalid,
+				"cursor order column [%s] not found in result", order.Field)
+		}
+
+		values[k] = v
+	}
+
+	cursor, err := EncodeCursor(values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	detail.Extras["has_more"] = true
+	detail.Extras["cursor"] = cursor
+
+	return rows, &detail, nil
+}
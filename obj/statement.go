@@ -0,0 +1,29 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj
+
+// Statement 生成的查询语句
+type Statement struct {
+	Query  string        `json:"query"`            // sql 语句、es 请求（方法 + 路径）、redis 命令
+	Params []interface{} `json:"params,omitempty"` // sql 绑定参数、es 请求体、redis 命令参数
+}
+
+// DryRun 试运行结果，包含将要发往数据库的所有语句
+type DryRun struct {
+	DB         string       `json:"db"`         // 数据库名称
+	Address    string       `json:"address"`    // 数据库地址（host:port），不包含账号密码
+	DSN        string       `json:"dsn"`        // 数据库连接串，密码以 *** 代替
+	Statements []*Statement `json:"statements"` // 按执行顺序排列的语句
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package obj
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/log"
+)
+
+// Compensation 补偿函数，跨库事务部分提交时，对已提交的 db 执行补偿逻辑（比如反向冲正）
+type Compensation func(ctx context.Context) error
+
+// compensateTimeout 每个补偿函数的执行超时时间
+const compensateTimeout = 30 * time.Second
+
+// PartialCommitError 跨库事务部分提交错误，部分 db 已提交，提交失败的 db 及其后的 db 已回滚
+type PartialCommitError struct {
+	Committed      []string         // 已提交的 db
+	Failed         string           // 提交失败的 db
+	RolledBack     []string         // 已回滚的 db（提交失败的 db 之后的所有 db）
+	Err            error            // 提交失败原因
+	CompensateErrs map[string]error // 补偿失败的 db 及其错误
+}
+
+// Error 实现 error 接口
+func (e *PartialCommitError) Error() string {
+	msg := strings.Builder{}
+	msg.WriteString("transaction partial committed, committed=[")
+	msg.WriteString(strings.Join(e.Committed, ","))
+	msg.WriteString("], failed=[")
+	msg.WriteString(e.Failed)
+	msg.WriteString("], rolled_back=[")
+	msg.WriteString(strings.Join(e.RolledBack, ","))
+	msg.WriteString("], error=[")
+	msg.WriteString(errs.Msg(e.Err))
+	msg.WriteString("]")
+
+	if len(e.CompensateErrs) > 0 {
+		msg.WriteString(", compensate failed=[")
+		var i int
+		for name, err := range e.CompensateErrs {
+			if i > 0 {
+				msg.WriteString(",")
+			}
+			msg.WriteString(fmt.Sprintf("%s: %s", name, errs.Msg(err)))
+			i++
+		}
+		msg.WriteString("]")
+	}
+
+	return msg.String()
+}
+
+// Unwrap 返回提交失败原因
+func (e *PartialCommitError) Unwrap() error {
+	return e.Err
+}
+
+// SetDBName 设置参与事务的 db 名称，用于日志与错误信息
+func (ti *TransInfo) SetDBName(dsn, name string) {
+	if ti.Names == nil {
+		ti.Names = map[string]string{}
+	}
+
+	ti.Names[dsn] = name
+}
+
+// GetDBName 获取参与事务的 db 名称，未设置名称时返回 dsn 在事务中的序号
+func (ti *TransInfo) GetDBName(dsn string) string {
+	if name, ok := ti.Names[dsn]; ok && name != "" {
+		return name
+	}
+
+	for k, v := range ti.DBs {
+		if v == dsn {
+			return fmt.Sprintf("db#%d", k+1)
+		}
+	}
+
+	return "unknown"
+}
+
+// AddCompensation 注册 dsn 的补偿函数，事务部分提交时，如果该 dsn 已提交，将按注册的逆序执行补偿函数
+func (ti *TransInfo) AddCompensation(dsn string, fn Compensation) {
+	if ti.Compensations == nil {
+		ti.Compensations = map[string][]Compensation{}
+	}
+
+	ti.Compensations[dsn] = append(ti.Compensations[dsn], fn)
+}
+
+// commit 按 db 参与事务的顺序依次提交。如果某个 db 提交失败，剩余未提交的 db 全部回滚；
+// 如果此前已有 db 提交成功，则返回 PartialCommitError，并对已提交的 db 按提交的逆序执行补偿函数。
+func (ti *TransInfo) commit() error {
+	ctx := ti.context()
+	multi := len(ti.DBs) > 1
+
+	var committed []string
+
+	for k, dsn := range ti.DBs {
+		cli := ti.GetTxClient(dsn)
+		if cli == nil {
+			continue
+		}
+
+		err := cli.FinishTx(nil)
+		if err == nil {
+			committed = append(committed, dsn)
+			if multi {
+				log.Infof(ctx, "transaction db [%s] committed", ti.GetDBName(dsn))
+			}
+			continue
+		}
+
+		rest := ti.DBs[k+1:]
+		rbErr := ti.rollback(err, rest)
+
+		if len(committed) == 0 { // 没有 db 提交成功，事务整体回滚
+			return rbErr
+		}
+
+		partial := PartialCommitError{Failed: ti.GetDBName(dsn), Err: err}
+		for _, v := range committed {
+			partial.Committed = append(partial.Committed, ti.GetDBName(v))
+		}
+		for _, v := range rest {
+			partial.RolledBack = append(partial.RolledBack, ti.GetDBName(v))
+		}
+
+		partial.CompensateErrs = ti.compensate(ctx, committed)
+
+		log.Errorf(ctx, errs.ErrTransaction, "%s", partial.Error())
+		return &partial
+	}
+
+	return nil
+}
+
+// rollback 回滚 dsns，返回 err，若有 db 回滚失败，返回回滚错误
+func (ti *TransInfo) rollback(err error, dsns []string) error {
+	ret := err
+
+	for _, dsn := range dsns {
+		cli := ti.GetTxClient(dsn)
+		if cli == nil {
+			continue
+		}
+
+		if e := cli.FinishTx(err); e != nil && e != err {
+			ret = e
+		}
+	}
+
+	return ret
+}
+
+// compensate 对已提交的 db 按提交的逆序执行补偿函数，返回补偿失败的 db 及错误
+func (ti *TransInfo) compensate(ctx context.Context, committed []string) map[string]error {
+	var failed map[string]error
+
+	for i := len(committed) - 1; i >= 0; i-- {
+		dsn := committed[i]
+		fns := ti.Compensations[dsn]
+
+		for j := len(fns) - 1; j >= 0; j-- {
+			err := runCompensation(fns[j])
+			if err != nil {
+				if failed == nil {
+					failed = map[string]error{}
+				}
+
+				failed[ti.GetDBName(dsn)] = err
+				log.Errorf(ctx, errs.ErrTransaction,
+					"transaction db [%s] compensate error: %v", ti.GetDBName(dsn), err)
+				break
+			}
+		}
+
+		if len(fns) > 0 && failed[ti.GetDBName(dsn)] == nil {
+			log.Infof(ctx, "transaction db [%s] compensated", ti.GetDBName(dsn))
+		}
+	}
+
+	return failed
+}
+
+// runCompensation 执行补偿函数，补偿使用独立的上下文，不受已超时或被取消的事务上下文影响
+func runCompensation(fn Compensation) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), compensateTimeout)
+	defer cancel()
+
+	defer func() {
+		if e := recover(); e != nil {
+			err = errs.Newf(errs.ErrPanic, "compensation panic: %v", e)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func (ti *TransInfo) context() context.Context {
+	if ti.Ctx != nil {
+		return ti.Ctx
+	}
+	return context.Background()
+}
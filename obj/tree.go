@@ -15,6 +15,9 @@
 package obj
 
 import (
+	"context"
+	"database/sql"
+
 	"github.com/horm-database/common/consts"
 	"github.com/horm-database/common/errs"
 	"github.com/horm-database/common/proto"
@@ -63,14 +66,27 @@ type Property struct {
 	Tables []string
 	DB     *TblDB
 	Table  *TblTable
+
+	Receiver interface{} // 结果接收者，支持的数据库可以直接将结果写入接收者，此时查询结果即为接收者本身
+	Tag      string      // 结果直接写入结构体接收者时，结构体字段与列名映射的 tag（编解码器的 tag）
 }
 
 // TransInfo 事务信息
 type TransInfo struct {
-	Trans     *Tree                    // 事务下的查询节点
-	Rollback  bool                     // 是否回滚
-	TxClients map[string]client.Client // sql 事务执行 client，（目前仅支持 sql 的事务 commit、rollback）
-	DBs       []string                 // 参与事务的所有 db。
+	Trans      *Tree                    // 事务下的查询节点
+	Rollback   bool                     // 是否回滚
+	TxClients  map[string]client.Client // sql 事务执行 client，（目前仅支持 sql 的事务 commit、rollback）
+	DBs        []string                 // 参与事务的所有 db。
+	Ctx        context.Context          // 事务上下文，sql 事务开启后会一直使用该上下文，直到事务结束
+	Cancel     context.CancelFunc       // 事务超时上下文的 cancel 函数，事务结束时调用
+	Options    *sql.TxOptions           // 事务选项，包括隔离级别、是否只读
+	Finished   bool                     // 事务是否已结束（已提交或已回滚）
+	Savepoints []string                 // 嵌套事务保存点栈，后加入事务的 db 开启事务后也会依次创建这些保存点
+
+	RollbackSavepoints map[string]bool // 被标记为只能回滚的嵌套事务保存点
+
+	Names         map[string]string         // 参与事务的 db 名称，key 为 dsn
+	Compensations map[string][]Compensation // 补偿函数，key 为 dsn，部分提交时对已提交的 db 执行
 }
 
 func (ti *TransInfo) GetTxClient(dsn string) client.Client {
@@ -96,10 +112,118 @@ func (ti *TransInfo) SetTxClient(dsn string, cli client.Client) {
 
 func (ti *TransInfo) ResetTxClient() {
 	ti.Rollback = false
+	ti.RollbackSavepoints = nil
 	ti.TxClients = map[string]client.Client{}
 	ti.DBs = []string{}
 }
 
+// Finish 结束事务，err 为 nil 时按参与顺序提交所有 db（参考 commit），否则回滚所有 db。
+func (ti *TransInfo) Finish(err error) error {
+	var ret error
+	if err == nil {
+		ret = ti.commit()
+	} else {
+		ret = ti.rollback(err, ti.DBs)
+	}
+
+	ti.Finished = true
+	ti.Savepoints = nil
+	ti.RollbackSavepoints = nil
+
+	if ti.Cancel != nil {
+		ti.Cancel()
+	}
+
+	return ret
+}
+
+// Savepoint 在所有参与事务的 db 上创建保存点，并压入保存点栈
+func (ti *TransInfo) Savepoint(ctx context.Context, name string) error {
+	for _, dsn := range ti.DBs {
+		cli := ti.GetTxClient(dsn)
+		if cli == nil {
+			continue
+		}
+
+		if err := cli.Savepoint(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	ti.Savepoints = append(ti.Savepoints, name)
+	return nil
+}
+
+// RollbackTo 所有参与事务的 db 回滚到保存点，并释放该保存点
+func (ti *TransInfo) RollbackTo(ctx context.Context, name string) error {
+	if err := ti.checkSavepoint(name); err != nil {
+		return err
+	}
+
+	for _, dsn := range ti.DBs {
+		cli := ti.GetTxClient(dsn)
+		if cli == nil {
+			continue
+		}
+
+		if err := cli.RollbackTo(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return ti.ReleaseSavepoint(ctx, name)
+}
+
+// ReleaseSavepoint 所有参与事务的 db 释放保存点，并弹出保存点栈
+func (ti *TransInfo) ReleaseSavepoint(ctx context.Context, name string) error {
+	if err := ti.checkSavepoint(name); err != nil {
+		return err
+	}
+
+	for _, dsn := range ti.DBs {
+		cli := ti.GetTxClient(dsn)
+		if cli == nil {
+			continue
+		}
+
+		if err := cli.ReleaseSavepoint(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	ti.Savepoints = ti.Savepoints[:len(ti.Savepoints)-1]
+	delete(ti.RollbackSavepoints, name)
+	return nil
+}
+
+// MarkRollback 将事务标记为只能回滚，savepoint 不为空时只标记该嵌套事务，回滚到保存点后标记即清除
+func (ti *TransInfo) MarkRollback(savepoint string) {
+	if savepoint == "" {
+		ti.Rollback = true
+		return
+	}
+
+	if ti.RollbackSavepoints == nil {
+		ti.RollbackSavepoints = map[string]bool{}
+	}
+
+	ti.RollbackSavepoints[savepoint] = true
+}
+
+// HasSavepoint 保存点是否还在保存点栈中
+func (ti *TransInfo) HasSavepoint(name string) bool {
+	return lo.IndexOf(ti.Savepoints, name) != -1
+}
+
+// 嵌套事务必须由内向外结束，只能操作栈顶的保存点
+func (ti *TransInfo) checkSavepoint(name string) error {
+	l := len(ti.Savepoints)
+	if l == 0 || ti.Savepoints[l-1] != name {
+		return errs.Newf(errs.ErrTransaction, "savepoint %s is not the innermost transaction", name)
+	}
+	return nil
+}
+
 // SetProperty 设置查询属性
 func (node *Tree) SetProperty(property *Property) *Tree {
 	node.Property = property
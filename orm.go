@@ -18,20 +18,26 @@ package orm
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/horm-database/common/consts"
 	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/proto"
 	"github.com/horm-database/common/types"
 	"github.com/horm-database/common/util"
 	"github.com/horm-database/go-horm/horm"
 	"github.com/horm-database/orm/obj"
 )
 
-// ORM 统一接入协议 本地 orm 的实现
+// ORM 统一接入协议 本地 orm 的实现。ORM 的语句构建方法会修改自身，同一个 ORM 不能在多个 goroutine 中并发使用，
+// 并发场景下请通过 Session 为每次调用创建独立的会话。
 type ORM struct {
-	db      *obj.TblDB
-	query   *horm.Query
-	initErr error
+	db        *obj.TblDB
+	query     *horm.Query
+	tx        *obj.TransInfo // 事务信息，不为 nil 时所有语句都在该事务中执行
+	savepoint string         // 嵌套事务保存点，不为空时表示本句柄是嵌套事务
+	subs      []*ORM         // 嵌套子查询
+	initErr   error
 }
 
 // NewORM 创建 local orm 客户端
@@ -85,30 +91,73 @@ func NewORM(dbName string) *ORM {
 	return &c
 }
 
+// Session 创建一个新的会话，会话与原 ORM 共享已解析的数据库配置（连接池按 dsn 全局复用）及事务信息，
+// 但拥有独立的语句，可以在各自的 goroutine 中构建、执行语句而互不影响，例如：
+//
+//	var db = orm.NewORM("db")
+//
+//	func GetUser(ctx context.Context, id int) (*User, error) {
+//		var user User
+//		_, err := db.Session().Name("user").Find(horm.Where{"id": id}).Exec(ctx, &user)
+//		return &user, err
+//	}
+//
+// 注意：事务句柄的 Session 与原句柄处于同一个事务中，事务连接不支持并发执行语句。
+func (o *ORM) Session() *ORM {
+	return &ORM{
+		db:        o.db,
+		query:     horm.NewQuery(""),
+		tx:        o.tx,
+		savepoint: o.savepoint,
+		initErr:   o.initErr,
+	}
+}
+
 // Exec 单执行单元 result 接收结果的指针
 func (o *ORM) Exec(ctx context.Context, retReceiver ...interface{}) (isNil bool, err error) {
-	if o.initErr != nil {
-		return false, o.initErr
+	var receiver interface{}
+	if len(retReceiver) == 1 && reflect.ValueOf(retReceiver[0]).Kind() == reflect.Ptr {
+		receiver = retReceiver[0]
+	}
+
+	return o.exec(ctx, receiver, func(ret interface{}) error {
+		if receiver != nil && ret == receiver { // 结果已直接写入接收者
+			return nil
+		}
+		return o.decode(ret, retReceiver...)
+	})
+}
+
+// exec 执行语句，并将解析后的结果交由 decode 解码。receiver 不为 nil 时，数据库可以将结果直接写入 receiver（参考 obj.Property）
+func (o *ORM) exec(ctx context.Context,
+	receiver interface{}, decode func(ret interface{}) error) (isNil bool, err error) {
+	if err = o.checkExec(); err != nil {
+		o.reset()
+		return false, err
 	}
 
 	defer func() {
 		if e := recover(); e != nil {
 			err = errs.New(errs.ErrPanic, fmt.Sprintf("%v", e))
+			if o.tx != nil { // 事务中发生 panic，事务只能回滚，嵌套事务只标记其保存点
+				o.tx.MarkRollback(o.savepoint)
+			}
 		}
-		o.query.Reset()
+		o.reset()
 	}()
 
-	if o.query.Unit.Size < 0 {
-		o.query.Unit.Size = 0
-	}
-
-	tree := &obj.Tree{}
-	err = initTree(tree, o.query.Unit, o.db)
+	tree, err := o.newTree()
 	if err != nil {
 		return false, err
 	}
 
-	tree.Result, tree.Detail, tree.IsNil, tree.Error = query(ctx, tree)
+	// 使用默认编解码器且没有嵌套子查询（需要在结果中挂载子查询结果）时，结果才可以直接写入接收者
+	if receiver != nil && o.query.Coder == nil && !tree.HasSub {
+		tree.Property.Receiver = receiver
+		tree.Property.Tag = o.query.GetCoder().GetTag() // 与默认编解码器使用相同的 tag，codec.SetDefaultTag 同样生效
+	}
+
+	execNode(ctx, tree)
 
 	var ret interface{}
 	isNil, ret, err = ParseResult(tree)
@@ -116,11 +165,87 @@ func (o *ORM) Exec(ctx context.Context, retReceiver ...interface{}) (isNil bool,
 		return isNil, err
 	}
 
-	err = o.query.GetCoder().Decode(o.query.ResultType, ret, retReceiver)
+	err = decode(ret)
 	if err != nil {
-		return false, errs.Newf(errs.ErrClientDecode,
-			"[request_id=%d] %v, result=[%s]", o.query.RequestID, err, types.ToString(ret))
+		return false, err
 	}
 
 	return isNil, nil
 }
+
+// decode 将结果解码到接收者
+func (o *ORM) decode(ret interface{}, retReceiver ...interface{}) error {
+	err := o.query.GetCoder().Decode(o.query.ResultType, ret, retReceiver)
+	if err != nil {
+		return errs.Newf(errs.ErrClientDecode,
+			"[request_id=%d] %v, result=[%s]", o.query.RequestID, err, types.ToString(ret))
+	}
+	return nil
+}
+
+// DryRun 试运行，返回将要发往数据库的语句、绑定参数及目标地址（密码已隐藏），不访问数据库。
+// 试运行不经过查询中间件，嵌套子查询依赖父查询结果，也不会生成。
+func (o *ORM) DryRun(ctx context.Context) (ret *obj.DryRun, err error) {
+	defer o.reset()
+
+	if o.initErr != nil {
+		return nil, o.initErr
+	}
+
+	tree, err := o.newTree()
+	if err != nil {
+		return nil, err
+	}
+
+	return dryRun(ctx, tree)
+}
+
+// checkExec 检查是否可以执行
+func (o *ORM) checkExec() error {
+	if o.initErr != nil {
+		return o.initErr
+	}
+
+	if o.tx != nil && o.finished() {
+		return errs.New(errs.ErrTransaction, "transaction has already been committed or rolled back")
+	}
+
+	return nil
+}
+
+// newTree 根据当前语句创建查询节点
+func (o *ORM) newTree() (*obj.Tree, error) {
+	return o.newTreeFromUnit(o.query.Unit)
+}
+
+// newTreeFromUnit 根据查询单元创建查询节点
+func (o *ORM) newTreeFromUnit(unit *proto.Unit) (*obj.Tree, error) {
+	if unit.Size < 0 {
+		unit.Size = 0
+	}
+
+	tree := &obj.Tree{TransInfo: o.tx, InTrans: o.tx != nil}
+	err := initTree(tree, unit, o.db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.subs) > 0 {
+		if err = o.initSubTree(tree); err != nil {
+			return nil, err
+		}
+	}
+
+	return tree, nil
+}
+
+// reset 重置语句及嵌套子查询
+func (o *ORM) reset() {
+	o.query.Reset()
+
+	for _, sub := range o.subs {
+		sub.reset()
+	}
+
+	o.subs = nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/horm-database/go-horm/horm"
+)
+
+// TestSessionConcurrent 多个 goroutine 通过同一个 ORM 的 Session 并发构建、试运行语句，
+// 每个会话的查询条件不能串到其他会话，需要配合 go test -race 运行。
+func TestSessionConcurrent(t *testing.T) {
+	db := NewORM("orm_test")
+	if db.initErr != nil {
+		t.Fatalf("new orm error: %v", db.initErr)
+	}
+
+	const goroutines, times = 32, 50
+
+	var wg sync.WaitGroup
+	errCh := make(chan string, goroutines)
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			for j := 0; j < times; j++ {
+				ret, err := db.Session().Name("user").Where(horm.Where{"id": id}).FindAll().DryRun(context.Background())
+				if err != nil {
+					errCh <- "dry run error: " + err.Error()
+					return
+				}
+
+				if len(ret.Statements) != 1 {
+					errCh <- "statements count is not 1"
+					return
+				}
+
+				params := ret.Statements[0].Params
+				if len(params) == 0 || params[0] != id {
+					errCh <- "where leaked across sessions: " + ret.Statements[0].Query
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for msg := range errCh {
+		t.Error(msg)
+	}
+}
@@ -39,6 +39,37 @@ func query(ctx context.Context, node *obj.Tree) (result interface{}, detail *pro
 	}()
 
 	realNode := node.GetReal()
+	request := newRequest(realNode)
+
+	// 走 db 查询
+	return database.QueryResult(ctx, request, realNode, realNode.GetDB().Addr, node.TransInfo)
+}
+
+// 节点试运行，只生成查询语句
+func dryRun(ctx context.Context, node *obj.Tree) (*obj.DryRun, error) {
+	realNode := node.GetReal()
+	request := newRequest(realNode)
+
+	return database.DryRunResult(ctx, request, realNode, realNode.GetDB().Addr, node.TransInfo)
+}
+
+// 节点流式查询
+func stream(ctx context.Context, node *obj.Tree, next func(row map[string]interface{}) error) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = errs.Newf(errs.ErrPanic, "stream panic: %v", e)
+			log.Errorf(ctx, errs.ErrPanic, "stream panic: %v", e)
+		}
+	}()
+
+	realNode := node.GetReal()
+	request := newRequest(realNode)
+
+	return database.StreamResult(ctx, request, realNode, realNode.GetDB().Addr, node.TransInfo, next)
+}
+
+// newRequest 根据查询节点生成数据库请求
+func newRequest(realNode *obj.Tree) *plugin.Request {
 	op := realNode.GetOp()
 	unit := realNode.GetUnit()
 
@@ -74,8 +105,7 @@ func query(ctx context.Context, node *obj.Tree) (result interface{}, detail *pro
 	request.Query = unit.Query
 	request.Args = unit.Args
 
-	// 走 db 查询
-	return database.QueryResult(ctx, request, realNode, realNode.GetDB().Addr, node.TransInfo)
+	return request
 }
 
 // 初始化分析树
@@ -61,6 +61,21 @@ func (o *ORM) Replace(data interface{}) *ORM {
 	return o
 }
 
+// Upsert (batch) （批量）插入数据，数据已存在（主键或唯一索引冲突）时更新，参数可以是 struct / []struct / Map / []Map。
+// updateColumns 为冲突时更新的列，不指定时更新所有插入的列（冲突列除外），包含 = 的为表达式，原样输出，
+// 例如 "count=count+1"。postgresql、oracle 需要通过 OnConflict 指定冲突列，elastic 相当于 index（存在则覆盖），
+// 不支持指定 updateColumns，redis 不支持 upsert。
+func (o *ORM) Upsert(data interface{}, updateColumns ...string) *ORM {
+	o.query.Insert(data)
+	o.query.SetParam("upsert", true)
+
+	if len(updateColumns) > 0 {
+		o.query.SetParam("update_columns", updateColumns)
+	}
+
+	return o
+}
+
 // Update 更新数据，参数可以是 struct / Map
 func (o *ORM) Update(data interface{}, where ...horm.Where) *ORM {
 	o.query.Update(data, where...)
@@ -118,6 +133,15 @@ func (o *ORM) Limit(limit int, offset ...uint64) *ORM {
 	return o
 }
 
+// Cursor 游标分页，cursor 为上一页返回的游标（Detail.Extras["cursor"]），首页传空字符串，size 为每页条数。
+// 按 Order 排序（排序列组合必须唯一，未设置时为 id 升序），不执行 count 查询，也不使用 OFFSET，
+// 返回的 Detail.Extras 中 has_more 表示是否还有下一页，cursor 为下一页的游标。
+func (o *ORM) Cursor(cursor string, size int) *ORM {
+	o.query.Limit(size)
+	o.query.SetParam("cursor", cursor)
+	return o
+}
+
 // Order 排序, 首字母 + 表示升序， - 表示降序
 func (o *ORM) Order(orders ...string) *ORM {
 	o.query.Order(orders...)
@@ -142,6 +166,82 @@ func (o *ORM) SetParam(key string, value interface{}) *ORM {
 	return o
 }
 
+// OnConflict 冲突判断列（唯一索引或主键），用于 replace、upsert。postgresql、sqlite 生成
+// INSERT ... ON CONFLICT (columns) DO UPDATE 语句，oracle 生成 MERGE INTO 语句，postgresql、oracle 必须指定。
+func (o *ORM) OnConflict(columns ...string) *ORM {
+	o.query.SetParam("on_conflict", columns)
+	return o
+}
+
+// Returning 返回 insert、replace、update、delete 修改后的数据（postgresql、sqlite 支持），
+// 结果与 FindAll 一致，可以通过 Exec 的接收者接收。
+func (o *ORM) Returning(columns ...string) *ORM {
+	o.query.SetParam("returning", columns)
+	return o
+}
+
+// ForUpdate 查询加排他锁 SELECT ... FOR UPDATE，锁在事务结束时释放，必须在事务中使用，仅 sql 的 Find、FindAll、Iter 支持加锁。
+// oracle 的行锁不能与分页一起使用，只支持不指定条数的 FindAll、Iter。
+func (o *ORM) ForUpdate() *ORM {
+	o.query.SetParam("lock", "update")
+	return o
+}
+
+// ForShare 查询加共享锁 SELECT ... FOR SHARE，必须在事务中使用
+func (o *ORM) ForShare() *ORM {
+	o.query.SetParam("lock", "share")
+	return o
+}
+
+// SkipLocked 跳过已被其他事务锁定的行，与 ForUpdate、ForShare 一起使用，未设置锁时默认为 ForUpdate，适用于任务队列
+func (o *ORM) SkipLocked() *ORM {
+	o.query.SetParam("lock_wait", "skip_locked")
+	return o
+}
+
+// NoWait 行已被其他事务锁定时立即返回失败而不等待，与 ForUpdate、ForShare 一起使用，未设置锁时默认为 ForUpdate
+func (o *ORM) NoWait() *ORM {
+	o.query.SetParam("lock_wait", "nowait")
+	return o
+}
+
+// Distinct 查询去重 SELECT DISTINCT，Count 以及分页查询的总数为去重后的条数
+func (o *ORM) Distinct() *ORM {
+	o.query.SetParam("distinct", true)
+	return o
+}
+
+// ForceIndex 强制使用索引 FORCE INDEX (indexes)，sqlite 只支持一个索引（INDEXED BY）
+func (o *ORM) ForceIndex(indexes ...string) *ORM {
+	o.query.SetParam("force_index", indexes)
+	return o
+}
+
+// UseIndex 建议使用索引 USE INDEX (indexes)，仅 mysql 支持
+func (o *ORM) UseIndex(indexes ...string) *ORM {
+	o.query.SetParam("use_index", indexes)
+	return o
+}
+
+// IgnoreIndex 忽略索引 IGNORE INDEX (indexes)，仅 mysql 支持
+func (o *ORM) IgnoreIndex(indexes ...string) *ORM {
+	o.query.SetParam("ignore_index", indexes)
+	return o
+}
+
+// MaxExecutionTime 查询最大执行时间（毫秒），生成优化器提示 /*+ MAX_EXECUTION_TIME(ms) */，仅 mysql 5.7 以上支持
+func (o *ORM) MaxExecutionTime(ms int) *ORM {
+	o.query.SetParam("max_execution_time", ms)
+	return o
+}
+
+// Hint 优化器提示，生成 SELECT /*+ hints */，例如 Hint("BKA(t1)", "NO_ICP(t1)")，oracle 的 Hint("INDEX(t idx)")，
+// 仅 mysql、oracle 支持，其他数据库返回错误
+func (o *ORM) Hint(hints ...string) *ORM {
+	o.query.SetParam("optimizer_hints", hints)
+	return o
+}
+
 // Source 直接输入查询语句查询
 func (o *ORM) Source(q string, args ...interface{}) *ORM {
 	o.query.Source(q, args...)
@@ -0,0 +1,311 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/common/log"
+	"github.com/horm-database/go-horm/horm"
+	"github.com/horm-database/orm/database/sql/client"
+	"github.com/horm-database/orm/obj"
+)
+
+// errRollback 主动回滚事务
+var errRollback = errors.New("transaction rollback")
+
+// 闭包事务遇到死锁等可重试错误时的重试策略
+const (
+	defaultMaxRetry  = 3                      // 默认最大重试次数
+	retryBaseBackoff = 20 * time.Millisecond  // 重试退避基数
+	retryMaxBackoff  = 500 * time.Millisecond // 重试最大退避时间
+)
+
+// TxOptions 事务选项
+type TxOptions struct {
+	Isolation sql.IsolationLevel // 隔离级别，比如 sql.LevelRepeatableRead、sql.LevelSerializable，默认使用数据库的默认隔离级别
+	ReadOnly  bool               // 是否只读事务
+	Timeout   time.Duration      // 事务超时时间，从开启事务开始计时，超时后事务自动回滚，0 表示不限制
+	MaxRetry  int                // 闭包事务遇到死锁、锁等待超时、序列化冲突时的最大重试次数，0 使用默认值 3，小于 0 不重试
+}
+
+// Begin 开启事务，返回事务句柄，在事务句柄上 Exec 的所有语句都在同一个事务中执行，直到调用 Commit 或 Rollback。
+// 在事务句柄上再次调用 Begin 会开启嵌套事务，嵌套事务通过保存点（SAVEPOINT）实现，回滚时仅撤销嵌套事务内的修改。
+// opts 可以指定事务隔离级别、只读、超时时间，嵌套事务沿用外层事务的选项，忽略 opts。
+// 注意：仅支持带事务功能的数据库（mysql、postgresql、clickhouse 等 sql 类数据库），ctx 在事务结束前必须保持有效。
+func (o *ORM) Begin(ctx context.Context, opts ...*TxOptions) (*ORM, error) {
+	if o.initErr != nil {
+		return nil, o.initErr
+	}
+
+	if o.tx != nil {
+		return o.beginNested(ctx)
+	}
+
+	transInfo := obj.TransInfo{Ctx: ctx}
+
+	if len(opts) > 0 && opts[0] != nil {
+		opt := opts[0]
+		transInfo.Options = &sql.TxOptions{Isolation: opt.Isolation, ReadOnly: opt.ReadOnly}
+
+		if opt.Timeout > 0 {
+			transInfo.Ctx, transInfo.Cancel = context.WithTimeout(ctx, opt.Timeout)
+		}
+	}
+
+	tx := ORM{
+		db:    o.db,
+		query: horm.NewQuery(""),
+		tx:    &transInfo,
+	}
+
+	return &tx, nil
+}
+
+// beginNested 开启嵌套事务，在所有已参与事务的 db 上创建保存点
+func (o *ORM) beginNested(ctx context.Context) (*ORM, error) {
+	if err := o.checkTx(); err != nil {
+		return nil, err
+	}
+
+	savepoint := fmt.Sprintf("horm_sp_%d", len(o.tx.Savepoints)+1)
+
+	err := o.tx.Savepoint(ctx, savepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := ORM{
+		db:        o.db,
+		query:     horm.NewQuery(""),
+		tx:        o.tx,
+		savepoint: savepoint,
+	}
+
+	return &tx, nil
+}
+
+// Commit 提交事务，如果事务已被标记为回滚（比如事务中的语句发生了 panic），则回滚事务并返回错误。
+// 嵌套事务提交时仅释放保存点，修改随最外层事务一起提交，嵌套事务被标记为回滚时只回滚到其保存点。
+func (o *ORM) Commit() error {
+	if err := o.checkTx(); err != nil {
+		return err
+	}
+
+	if o.savepoint != "" {
+		if o.tx.RollbackSavepoints[o.savepoint] {
+			err := errs.New(errs.ErrTransaction, "nested transaction is marked as rollback only")
+			if rbErr := o.tx.RollbackTo(o.txCtx(), o.savepoint); rbErr != nil {
+				return rbErr
+			}
+			return err
+		}
+
+		return o.tx.ReleaseSavepoint(o.txCtx(), o.savepoint)
+	}
+
+	if o.tx.Rollback {
+		err := errs.New(errs.ErrTransaction, "transaction is marked as rollback only")
+		return o.tx.Finish(err)
+	}
+
+	if o.tx.Ctx != nil && o.tx.Ctx.Err() != nil { // 事务超时或上下文被取消，数据库事务已被自动回滚
+		err := errs.Newf(errs.ErrTransaction, "transaction has been rolled back: %v", o.tx.Ctx.Err())
+		return o.tx.Finish(err)
+	}
+
+	return o.tx.Finish(nil)
+}
+
+// Rollback 回滚事务，嵌套事务仅回滚到其保存点，外层事务的修改不受影响。
+func (o *ORM) Rollback() error {
+	if err := o.checkTx(); err != nil {
+		return err
+	}
+
+	if o.savepoint != "" {
+		return o.tx.RollbackTo(o.txCtx(), o.savepoint)
+	}
+
+	err := o.tx.Finish(errRollback)
+	if err == errRollback {
+		return nil
+	}
+
+	return err
+}
+
+// Transaction 闭包事务，fn 返回 nil 时提交事务，fn 返回 error 或 panic 时回滚事务。
+// fn 中所有语句必须通过参数 tx 执行，才会在同一个事务中。opts 同 Begin。
+// 事务遇到死锁、锁等待超时、序列化冲突时，会按随机退避重新执行整个 fn，因此 fn 需要可以重复执行。
+// 嵌套事务不重试，由最外层事务统一重试。
+func (o *ORM) Transaction(ctx context.Context, fn func(tx *ORM) error, opts ...*TxOptions) error {
+	maxRetry := defaultMaxRetry
+	if len(opts) > 0 && opts[0] != nil && opts[0].MaxRetry != 0 {
+		maxRetry = opts[0].MaxRetry
+	}
+
+	if o.tx != nil {
+		maxRetry = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := o.transaction(ctx, fn, opts...)
+		if attempt >= maxRetry || !retryable(err) {
+			return err
+		}
+
+		log.Warnf(ctx, "transaction retry %d times, error: %v", attempt+1, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryBackoff(attempt)):
+		}
+	}
+}
+
+// transaction 执行一次闭包事务
+func (o *ORM) transaction(ctx context.Context, fn func(tx *ORM) error, opts ...*TxOptions) (err error) {
+	tx, err := o.Begin(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			err = errs.Newf(errs.ErrPanic, "transaction panic: %v", e)
+			log.Errorf(ctx, errs.ErrPanic, "transaction panic: %v", e)
+
+			if !tx.finished() {
+				if rbErr := tx.Rollback(); rbErr != nil {
+					err = rbErr
+				}
+			}
+		}
+	}()
+
+	err = fn(tx)
+	if tx.finished() { // fn 中已自行提交或回滚
+		return err
+	}
+
+	if err != nil {
+		// 死锁等错误发生时数据库可能已回滚整个事务，回滚错误以原始错误为准，以便外层事务重试
+		if rbErr := tx.Rollback(); rbErr != nil && !retryable(err) {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// WithTx 将当前 db 加入 tx 所在的事务，返回加入事务后的句柄，用于跨库事务，比如：
+//
+//	tx, _ := orderDB.Begin(ctx)
+//	ledger := ledgerDB.WithTx(tx)
+//
+// 提交时按 db 加入事务的顺序依次提交，若部分 db 已提交而后续 db 提交失败，
+// Commit 返回 *obj.PartialCommitError，并对已提交的 db 执行 OnCompensate 注册的补偿函数。
+func (o *ORM) WithTx(tx *ORM) *ORM {
+	c := ORM{
+		db:        o.db,
+		query:     horm.NewQuery(""),
+		tx:        tx.tx,
+		savepoint: tx.savepoint,
+		initErr:   o.initErr,
+	}
+
+	if c.initErr == nil && tx.tx == nil {
+		c.initErr = errs.New(errs.ErrTransaction, "WithTx: tx is not in transaction")
+	}
+
+	return &c
+}
+
+// OnCompensate 注册当前 db 的补偿函数，跨库事务部分提交时，如果当前 db 已经提交成功，
+// 则按注册的逆序执行补偿函数，用于撤销已提交的修改。补偿函数使用独立的上下文（超时时间 30 秒），不受事务上下文影响。
+func (o *ORM) OnCompensate(fn func(ctx context.Context) error) error {
+	if o.initErr != nil {
+		return o.initErr
+	}
+
+	if err := o.checkTx(); err != nil {
+		return err
+	}
+
+	o.tx.AddCompensation(o.db.Addr.Conn.DSN, fn)
+	return nil
+}
+
+// InTransaction 是否在事务中
+func (o *ORM) InTransaction() bool {
+	return o.tx != nil && !o.finished()
+}
+
+func (o *ORM) checkTx() error {
+	if o.tx == nil {
+		return errs.New(errs.ErrTransaction, "not in transaction")
+	}
+
+	if o.finished() {
+		return errs.New(errs.ErrTransaction, "transaction has already been committed or rolled back")
+	}
+
+	return nil
+}
+
+// finished 事务是否已结束，嵌套事务在其保存点被释放后即结束
+func (o *ORM) finished() bool {
+	if o.tx.Finished {
+		return true
+	}
+
+	return o.savepoint != "" && !o.tx.HasSavepoint(o.savepoint)
+}
+
+// retryable 事务失败后是否可以重新执行整个闭包。跨库事务部分提交时，已提交的 db 无法撤销，
+// 即使提交失败的原因是死锁、序列化冲突，也不能重试，否则已提交的修改会被重复执行。
+func retryable(err error) bool {
+	if errors.As(err, new(*obj.PartialCommitError)) {
+		return false
+	}
+
+	return client.IsRetryable(err)
+}
+
+// retryBackoff 第 attempt 次重试的退避时间，指数退避并加入随机抖动
+func retryBackoff(attempt int) time.Duration {
+	backoff := retryBaseBackoff << uint(attempt)
+	if backoff > retryMaxBackoff || backoff <= 0 {
+		backoff = retryMaxBackoff
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+func (o *ORM) txCtx() context.Context {
+	if o.tx.Ctx != nil {
+		return o.tx.Ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2024 The horm-database Authors. All rights reserved.
+// This file Author:  CaoHao <18500482693@163.com> .
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/horm-database/common/errs"
+	"github.com/horm-database/orm/database/sql/client"
+	"github.com/horm-database/orm/obj"
+)
+
+// TestRetryablePartialCommit 跨库事务部分提交时，即使后续 db 提交失败的原因是死锁，也不能重试整个事务
+func TestRetryablePartialCommit(t *testing.T) {
+	deadlock := errs.NewDB(client.ErrCodeDeadlock, "Deadlock found when trying to get lock")
+
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "deadlock", err: deadlock, want: true},
+		{name: "wrapped deadlock", err: fmt.Errorf("commit: %w", deadlock), want: true},
+		{name: "partial commit", err: &obj.PartialCommitError{
+			Committed: []string{"order"},
+			Failed:    "ledger",
+			Err:       deadlock,
+		}, want: false},
+		{name: "wrapped partial commit", err: fmt.Errorf("transfer: %w", &obj.PartialCommitError{
+			Committed: []string{"order"},
+			Failed:    "ledger",
+			Err:       errs.NewDB(client.ErrCodeSerializationFailure, "could not serialize access"),
+		}), want: false},
+		{name: "other error", err: errs.New(errs.ErrTransaction, "transaction rollback"), want: false},
+	}
+
+	for _, c := range cases {
+		if got := retryable(c.err); got != c.want {
+			t.Errorf("%s: retryable = %v, want %v", c.name, got, c.want)
+		}
+	}
+}